@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jkonarze/gobump/pkg/gobump"
+	"github.com/spf13/cobra"
+)
+
+var cmdAudit = &cobra.Command{
+	Use:   "audit [path]",
+	Short: "Check the repositories against the version policies",
+	Long: `Work out the target of every repository from the version policies, without touching them,
+and report whether it complies, failing when any repository doesn't`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := bumpOptions(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		b, err := gobump.New(opts)
+		if err != nil {
+			return err
+		}
+
+		plan, err := b.Plan(context.Background())
+		if err != nil {
+			return err
+		}
+
+		behind := 0
+		for _, rp := range plan.Repos {
+			if rp.Violates() {
+				behind++
+			}
+		}
+
+		if output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(plan.Repos); err != nil {
+				return err
+			}
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "REPO\tMODULE\tGO\tPOLICY\tTARGET\tSTATUS")
+			for _, rp := range plan.Repos {
+				policy := rp.Policy
+				if policy == "" {
+					policy = "version " + plan.Version
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rp.Name, rp.Module, rp.From, policy, rp.To, auditStatus(rp))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+
+		if behind > 0 {
+			return fmt.Errorf("%d of %d repositories don't comply", behind, len(plan.Repos))
+		}
+
+		return nil
+	},
+}
+
+func auditStatus(rp gobump.RepoPlan) string {
+	switch {
+	case rp.Error != "":
+		return "error: " + rp.Error
+	case rp.Complies:
+		return "ok"
+	case rp.Above:
+		return "above"
+	case rp.Skip != "":
+		return "skipped: " + rp.Skip
+	}
+
+	return "behind"
+}
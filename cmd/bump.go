@@ -1,17 +1,150 @@
 package cmd
 
 import (
-	"github.com/jkonarze/gobump/internal"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/jkonarze/gobump/pkg/gobump"
 	"github.com/spf13/cobra"
 )
 
 var cmdBump = &cobra.Command{
 	Use:   "bump [path]",
 	Short: "Bump version of go for project",
-	Long: `An easy way to update the go lang version for the project in the given path`,
-	Args: cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		svc := internal.NewWorker(args[0], version)
-		svc.Init()
+	Long: `An easy way to update the go lang version for the project in the given path,
+or for remote repositories given with --repos or --org which are cloned into a temporary workspace`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := bumpOptions(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		if campaign != "" {
+			if opts.Campaign, err = gobump.NewCampaign(opts.Config.StateDir, campaign, opts.Path, opts.Remotes, opts.Config); err != nil {
+				return err
+			}
+		}
+
+		return run(opts)
 	},
 }
+
+// bumpOptions reads the config, the repositories and resolves the target
+// version for bump and plan.
+func bumpOptions(cmd *cobra.Command, args []string) (gobump.Options, error) {
+	opts, err := repoOptions(cmd, args)
+	if err != nil {
+		return gobump.Options{}, err
+	}
+
+	cfg := &opts.Config
+	if cfg.Version, err = resolveVersion(*cfg, cfg.Version); err != nil {
+		return gobump.Options{}, err
+	}
+	if cfg.Policies, err = resolvePolicies(*cfg); err != nil {
+		return gobump.Options{}, err
+	}
+	slog.Info("bumping", "to", cfg.Version)
+
+	return opts, nil
+}
+
+// repoOptions reads the config and the repositories given as a path or
+// with --repos or --org.
+func repoOptions(cmd *cobra.Command, args []string) (gobump.Options, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return gobump.Options{}, err
+	}
+
+	remotes, err := remoteRepos(cfg)
+	if err != nil {
+		return gobump.Options{}, err
+	}
+
+	path := ""
+	switch {
+	case len(args) == 1 && remotes == nil:
+		if path, err = filepath.Abs(args[0]); err != nil {
+			return gobump.Options{}, err
+		}
+	case len(args) == 1:
+		return gobump.Options{}, errors.New("a path can't be combined with --repos or --org")
+	case remotes == nil:
+		return gobump.Options{}, errors.New("requires a path, --repos or --org")
+	}
+
+	return gobump.Options{Path: path, Remotes: remotes, Config: cfg}, nil
+}
+
+// run plans and applies the bump, failing when any repository failed.
+func run(opts gobump.Options) error {
+	stop, err := startProgress(&opts)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	b, err := gobump.New(opts)
+	if err != nil {
+		return err
+	}
+
+	plan, err := b.Plan(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return apply(b, plan)
+}
+
+// apply applies the plan, failing when any repository failed.
+func apply(b *gobump.Bumper, plan *gobump.Plan) error {
+	results, err := b.Apply(context.Background(), plan)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.Status == gobump.StatusFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// remoteRepos collects the repositories to clone, nil when bumping local
+// checkouts.
+func remoteRepos(cfg gobump.Config) ([]string, error) {
+	var remotes []string
+	if repoList != "" {
+		urls, err := gobump.ReadRepoList(repoList)
+		if err != nil {
+			return nil, err
+		}
+		remotes = append(remotes, urls...)
+	}
+
+	if org != "" {
+		urls, err := gobump.OrgRepos(context.Background(), cfg, org)
+		if err != nil {
+			return nil, err
+		}
+		remotes = append(remotes, urls...)
+	}
+
+	if remotes == nil && (repoList != "" || org != "") {
+		return nil, errors.New("no repositories to bump")
+	}
+
+	return remotes, nil
+}
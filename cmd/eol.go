@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jkonarze/gobump/pkg/gobump"
+	"github.com/spf13/cobra"
+)
+
+// noOwner stands for the team of repositories without code owners.
+const noOwner = "(no owner)"
+
+var cmdEOL = &cobra.Command{
+	Use:   "eol [path]",
+	Short: "Report the repositories running a go version past or near its end of life",
+	Long: `Classify the go version of every repository, its toolchain directive or else its go directive,
+as supported, nearing end of life or end of life and sum them up per team owning go.mod in CODEOWNERS.
+Go supports the two newest minor releases`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := repoOptions(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		index := gobump.NewReleaseIndex(opts.Config.IndexURL, opts.Config.IndexCache, offline)
+		minors, err := index.Minors()
+		if err != nil {
+			minors = gobump.KnownMinors()
+			slog.Warn("release index unavailable, using the built-in release table", "newest", minors[0], "err", err)
+		}
+
+		b, err := gobump.New(opts)
+		if err != nil {
+			return err
+		}
+
+		statuses, err := b.EOL(context.Background(), minors, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(statuses)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "REPO\tGO\tTOOLCHAIN\tSTATUS\tEOL\tTEAMS")
+		for _, s := range statuses {
+			status, eol := s.Status, s.EOL
+			if s.Error != "" {
+				status = "error: " + s.Error
+			}
+			if s.Estimated {
+				eol = "~" + eol
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Repo, s.Go, s.Toolchain, status, eol, strings.Join(s.Teams, ","))
+		}
+		fmt.Fprintln(tw)
+
+		fmt.Fprintf(tw, "TEAM\t%s\t%s\t%s\n", strings.ToUpper(gobump.EOLSupported), strings.ToUpper(gobump.EOLNearing), strings.ToUpper(gobump.EOLEnded))
+		summary := eolSummary(statuses)
+		teams := make([]string, 0, len(summary))
+		for team := range summary {
+			teams = append(teams, team)
+		}
+		sort.Strings(teams)
+		for _, team := range teams {
+			counts := summary[team]
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", team, counts[gobump.EOLSupported], counts[gobump.EOLNearing], counts[gobump.EOLEnded])
+		}
+
+		return tw.Flush()
+	},
+}
+
+// eolSummary counts the statuses of the repositories of every team, a
+// repository owned by several teams counts for each of them.
+func eolSummary(statuses []gobump.EOLStatus) map[string]map[string]int {
+	summary := map[string]map[string]int{}
+	for _, s := range statuses {
+		if s.Status == "" {
+			continue
+		}
+
+		teams := s.Teams
+		if len(teams) == 0 {
+			teams = []string{noOwner}
+		}
+		for _, team := range teams {
+			if summary[team] == nil {
+				summary[team] = map[string]int{}
+			}
+			summary[team][s.Status]++
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jkonarze/gobump/pkg/gobump"
+	"github.com/spf13/cobra"
+)
+
+var cmdMerge = &cobra.Command{
+	Use:   "merge [campaign]",
+	Short: "Merge the pull requests of a campaign which passed their checks and are approved",
+	Long:  `Merge every open pull request of the campaign, or of all campaigns, whose checks passed, which is approved and has no conflicts, and report why the others were left open`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gobump.LoadConfig(configPath)
+		if err != nil {
+			return err
+		}
+
+		names := args
+		if len(names) == 0 {
+			if names, err = gobump.ListCampaigns(cfg.StateDir); err != nil {
+				return err
+			}
+		}
+
+		failed := 0
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CAMPAIGN\tREPO\tRESULT\tURL")
+		for _, name := range names {
+			c, err := gobump.OpenCampaign(cfg.StateDir, name)
+			if err != nil {
+				return err
+			}
+
+			method := mergeMethod
+			if method == "" {
+				method = c.Config.PullRequest.AutoMerge
+			}
+			if method == "" {
+				method = "squash"
+			}
+
+			prs, err := c.Merge(context.Background(), method)
+			for _, pr := range prs {
+				result := pr.Blocker()
+				if pr.State == "merged" {
+					result = "merged"
+				}
+				if pr.Error != "" {
+					failed++
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, pr.Repo, result, pr.URL)
+			}
+			if err != nil {
+				tw.Flush()
+				return err
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d pull requests failed", failed)
+		}
+		return nil
+	},
+}
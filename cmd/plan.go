@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jkonarze/gobump/pkg/gobump"
+	"github.com/spf13/cobra"
+)
+
+var cmdPlan = &cobra.Command{
+	Use:   "plan [path]",
+	Short: "Plan a bump without touching the repositories",
+	Long: `Work out the change of every repository and write it to a plan file which
+gobump apply executes later on, refusing to if any planned file changed in between`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := bumpOptions(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		stop, err := startProgress(&opts)
+		if err != nil {
+			return err
+		}
+
+		b, err := gobump.New(opts)
+		if err != nil {
+			stop()
+			return err
+		}
+
+		plan, err := b.Plan(context.Background())
+		stop()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "REPO\tMODULE\tFROM\tTO\tFILES\tBRANCH\tNOTE")
+		for _, rp := range plan.Repos {
+			note := rp.Skip
+			if rp.Error != "" {
+				note = "error: " + rp.Error
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", rp.Name, rp.Module, rp.From, rp.To, len(rp.Edits), rp.Branch, note)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		return plan.Write(planFile)
+	},
+}
+
+var cmdApply = &cobra.Command{
+	Use:   "apply [plan.json]",
+	Short: "Apply a plan written by gobump plan",
+	Long:  `Execute exactly the given plan with the config it was made with, refusing to run when any planned file changed since planning`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plan, err := gobump.ReadPlan(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := plan.Check(context.Background()); err != nil {
+			return err
+		}
+
+		plan.Config.Log = logging
+		opts := gobump.Options{Config: plan.Config}
+		stop, err := startProgress(&opts)
+		if err != nil {
+			return err
+		}
+		defer stop()
+
+		b, err := gobump.New(opts)
+		if err != nil {
+			return err
+		}
+
+		return apply(b, plan)
+	},
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/jkonarze/gobump/pkg/gobump"
+	"github.com/spf13/cobra"
+)
+
+var cmdResume = &cobra.Command{
+	Use:   "resume [campaign]",
+	Short: "Resume an interrupted campaign",
+	Long:  `Run a campaign again with its original settings, skipping the repositories it already completed`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gobump.LoadConfig(configPath)
+		if err != nil {
+			return err
+		}
+
+		c, err := gobump.OpenCampaign(cfg.StateDir, args[0])
+		if err != nil {
+			return err
+		}
+
+		// the campaign keeps its settings but logs where this run asks to.
+		// Its policies were saved resolved, only those of campaigns saved
+		// before that are resolved again.
+		c.Config.Log = logging
+		if c.Config.Policies, err = resolvePolicies(c.Config); err != nil {
+			return err
+		}
+		return run(gobump.Options{Path: c.Path, Remotes: c.Remotes, Config: c.Config, Campaign: c})
+	},
+}
@@ -2,25 +2,281 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/jkonarze/gobump/pkg/gobump"
 	"github.com/spf13/cobra"
 )
 
 var (
-	path    string
-	version string
+	path        string
+	version     string
+	configPath  string
+	concurrency int
+	indexURL    string
+	indexCache  string
+	offline     bool
+	modernize   bool
+	force       bool
+	respectDeps bool
+	deps        string
+	campaign    string
+	output      string
+	repoList    string
+	org         string
+	worktree    bool
+	interactive bool
+	planFile    string
+	logging     gobump.Log
+	progress    bool
+	author      string
+	committer   string
+	sign        string
+	signingKey  string
+	signoff     bool
+	pr          gobump.PROptions
+	draft       bool
+	mergeMethod string
+	fork        string
 )
 
 func Execute() {
-	cmdBump.PersistentFlags().StringVarP(&path, "path", "p", "", "path to go repos")
-	cmdBump.PersistentFlags().StringVarP(&version, "version", "v", "1.14", "desire go version")
+	for _, cmd := range []*cobra.Command{cmdBump, cmdPlan} {
+		flags := cmd.Flags()
+		flags.StringVarP(&path, "path", "p", "", "path to go repos")
+		flags.StringVarP(&version, "version", "v", "stable", "desire go version: latest, stable, oldstable, 1.22.x or exact")
+		flags.IntVarP(&concurrency, "concurrency", "c", 30, "number of repos processed in parallel")
+		flags.StringVar(&indexURL, "index-url", gobump.DefaultIndexURL, "go release index url or local json file")
+		flags.StringVar(&indexCache, "index-cache", "", "release index cache file (default $XDG_CACHE_HOME/gobump/releases.json)")
+		flags.BoolVar(&modernize, "modernize", false, "rewrite go sources to use features unlocked by the new version")
+		flags.BoolVar(&force, "force", false, "bump even when the code uses APIs newer than a lower target version")
+		flags.BoolVar(&respectDeps, "respect-deps", false, "raise the target to the go version required by dependencies")
+		flags.StringVar(&deps, "deps", "", "update dependencies too: all, patch or a comma separated list of modules")
+		flags.StringVar(&repoList, "repos", "", "file listing remote repositories to clone and bump")
+		flags.StringVar(&org, "org", "", "clone and bump every go repository of the organization")
+		flags.BoolVar(&worktree, "worktree", false, "bump local repositories in a dedicated git worktree off the default branch")
+		flags.BoolVarP(&interactive, "interactive", "i", false, "review every repository's change before it is submitted")
+		flags.BoolVar(&offline, "offline", false, "resolve versions from the release index cache only")
+		flags.StringVar(&author, "author", "", "commit author as `Name <email>`")
+		flags.StringVar(&committer, "committer", "", "commit committer as `Name <email>` (default the author)")
+		flags.StringVar(&sign, "sign", "", "sign commits: gpg or ssh")
+		flags.StringVar(&signingKey, "signing-key", "", "gpg key id or ssh key file to sign with")
+		flags.BoolVar(&signoff, "signoff", false, "add a Signed-off-by trailer to commits")
+		flags.StringSliceVar(&pr.Labels, "label", nil, "pull request labels (default minor)")
+		flags.StringSliceVar(&pr.Reviewers, "reviewer", nil, "request reviews from these users")
+		flags.StringSliceVar(&pr.TeamReviewers, "team-reviewer", nil, "request reviews from these team slugs")
+		flags.StringSliceVar(&pr.Assignees, "assignee", nil, "assign the pull requests to these users")
+		flags.StringVar(&pr.Milestone, "milestone", "", "milestone title or number of the pull requests")
+		flags.BoolVar(&draft, "draft", false, "open the pull requests as drafts")
+		flags.StringVar(&pr.AutoMerge, "auto-merge", "", "enable auto-merge of the pull requests: squash, merge or rebase")
+		flags.StringVar(&fork, "fork", "", "push to a fork under this user or organization and open cross-repository pull requests")
+	}
+	cmdBump.Flags().StringVar(&campaign, "campaign", "", "record progress in a named campaign which can be resumed")
+	cmdPlan.Flags().StringVarP(&planFile, "output", "o", "plan.json", "file the plan is written to")
 
-	var rootCmd = &cobra.Command{Use: "gobump"}
+	var rootCmd = &cobra.Command{
+		Use:               "gobump",
+		SilenceErrors:     true,
+		SilenceUsage:      true,
+		PersistentPreRunE: setupLogging,
+	}
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "config file (default $XDG_CONFIG_HOME/gobump/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&logging.Level, "log-level", "info", "log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logging.Format, "log-format", "text", "log format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logging.Dir, "log-dir", "", "directory receiving the full command output of every repository")
+	rootCmd.PersistentFlags().BoolVar(&progress, "progress", true, "show a live progress view when stdout is a terminal")
 	rootCmd.AddCommand(cmdBump)
+	rootCmd.AddCommand(cmdResume)
+	rootCmd.AddCommand(cmdPlan)
+	rootCmd.AddCommand(cmdApply)
+
+	cmdStatus.Flags().StringVarP(&output, "output", "o", "text", "output format: text or json")
+	rootCmd.AddCommand(cmdStatus)
+
+	cmdMerge.Flags().StringVar(&mergeMethod, "method", "", "merge method: squash, merge or rebase (default the campaign's auto-merge or squash)")
+	rootCmd.AddCommand(cmdMerge)
+
+	for _, cmd := range []*cobra.Command{cmdAudit, cmdEOL} {
+		flags := cmd.Flags()
+		flags.IntVarP(&concurrency, "concurrency", "c", 30, "number of repos processed in parallel")
+		flags.StringVar(&indexURL, "index-url", gobump.DefaultIndexURL, "go release index url or local json file")
+		flags.StringVar(&indexCache, "index-cache", "", "release index cache file (default $XDG_CACHE_HOME/gobump/releases.json)")
+		flags.BoolVar(&offline, "offline", false, "read the release index from its cache only")
+		flags.StringVar(&repoList, "repos", "", "file listing remote repositories to clone and check")
+		flags.StringVar(&org, "org", "", "clone and check every go repository of the organization")
+		flags.StringVarP(&output, "output", "o", "text", "output format: text or json")
+		rootCmd.AddCommand(cmd)
+	}
+	cmdAudit.Flags().StringVarP(&version, "version", "v", "stable", "go version of the repositories no policy matches")
 
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, gobump.Redact(err.Error()))
 		os.Exit(1)
 	}
 }
+
+// setupLogging merges the log flags into the configured logging and makes
+// the resulting logger the default one.
+func setupLogging(cmd *cobra.Command, args []string) error {
+	cfg, err := gobump.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("log-level") || cfg.Log.Level == "" {
+		cfg.Log.Level = logging.Level
+	}
+	if flags.Changed("log-format") || cfg.Log.Format == "" {
+		cfg.Log.Format = logging.Format
+	}
+	if flags.Changed("log-dir") {
+		cfg.Log.Dir = logging.Dir
+	}
+	logging = cfg.Log
+
+	logger, err := gobump.NewLogger(logging, os.Stderr)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	return nil
+}
+
+// startProgress shows the progress view on a terminal, routing the logs
+// through it, and returns the func stopping it.
+func startProgress(opts *gobump.Options) (func(), error) {
+	if !progress || opts.Config.Interactive {
+		return func() {}, nil
+	}
+
+	// the logs move from stderr into the view, so both have to be terminals
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		fi, err := f.Stat()
+		if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+			return func() {}, nil
+		}
+	}
+
+	p := gobump.NewProgress(os.Stdout)
+	logger, err := gobump.NewLogger(logging, p)
+	if err != nil {
+		p.Stop()
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	opts.Progress, opts.Logger = p, logger
+
+	return p.Stop, nil
+}
+
+// loadConfig reads the global config and lets explicitly set flags override it.
+func loadConfig(cmd *cobra.Command) (gobump.Config, error) {
+	cfg, err := gobump.LoadConfig(configPath)
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.Log = logging
+
+	flags := cmd.Flags()
+	if flags.Changed("version") {
+		cfg.Version = version
+	}
+	if flags.Changed("concurrency") {
+		cfg.Concurrency = concurrency
+	}
+	if flags.Changed("modernize") {
+		cfg.Modernize = modernize
+	}
+	if flags.Changed("force") {
+		cfg.Force = force
+	}
+	if flags.Changed("respect-deps") {
+		cfg.RespectDeps = respectDeps
+	}
+	if flags.Changed("deps") {
+		cfg.Deps = parseDeps(deps)
+	}
+	if flags.Changed("worktree") {
+		cfg.Worktree = worktree
+	}
+	if flags.Changed("interactive") {
+		cfg.Interactive = interactive
+	}
+	if flags.Changed("index-url") {
+		cfg.IndexURL = indexURL
+	}
+	if flags.Changed("index-cache") {
+		cfg.IndexCache = indexCache
+	}
+	if flags.Changed("author") {
+		if cfg.Commit.Author, err = gobump.ParseIdentity(author); err != nil {
+			return cfg, err
+		}
+	}
+	if flags.Changed("committer") {
+		if cfg.Commit.Committer, err = gobump.ParseIdentity(committer); err != nil {
+			return cfg, err
+		}
+	}
+	if flags.Changed("sign") {
+		cfg.Commit.Sign = sign
+	}
+	if flags.Changed("signing-key") {
+		cfg.Commit.SigningKey = signingKey
+	}
+	if flags.Changed("signoff") {
+		cfg.Commit.Signoff = signoff
+	}
+	if flags.Changed("label") {
+		cfg.PullRequest.Labels = pr.Labels
+	}
+	if flags.Changed("reviewer") {
+		cfg.PullRequest.Reviewers = pr.Reviewers
+	}
+	if flags.Changed("team-reviewer") {
+		cfg.PullRequest.TeamReviewers = pr.TeamReviewers
+	}
+	if flags.Changed("assignee") {
+		cfg.PullRequest.Assignees = pr.Assignees
+	}
+	if flags.Changed("milestone") {
+		cfg.PullRequest.Milestone = pr.Milestone
+	}
+	if flags.Changed("draft") {
+		cfg.PullRequest.Draft = &draft
+	}
+	if flags.Changed("auto-merge") {
+		cfg.PullRequest.AutoMerge = pr.AutoMerge
+	}
+	if flags.Changed("fork") {
+		cfg.Fork = fork
+	}
+
+	return cfg, nil
+}
+
+func parseDeps(value string) gobump.Deps {
+	switch value {
+	case "", "all", "patch":
+		return gobump.Deps{Mode: value}
+	}
+
+	return gobump.Deps{Mode: "modules", Modules: strings.Split(value, ",")}
+}
+
+// resolveVersion turns a symbolic target such as `stable` into a go version.
+func resolveVersion(cfg gobump.Config, target string) (string, error) {
+	index := gobump.NewReleaseIndex(cfg.IndexURL, cfg.IndexCache, offline)
+	return index.Resolve(target)
+}
+
+// resolvePolicies resolves the targets of the version policies.
+func resolvePolicies(cfg gobump.Config) ([]gobump.Policy, error) {
+	index := gobump.NewReleaseIndex(cfg.IndexURL, cfg.IndexCache, offline)
+	return index.ResolvePolicies(cfg.Policies)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jkonarze/gobump/pkg/gobump"
+	"github.com/spf13/cobra"
+)
+
+var cmdStatus = &cobra.Command{
+	Use:   "status [campaign]",
+	Short: "Show the state of the pull requests opened by a campaign",
+	Long:  `Query the provider for every pull request of the campaign, or of all campaigns, and report its state, checks and reviews`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gobump.LoadConfig(configPath)
+		if err != nil {
+			return err
+		}
+
+		names := args
+		if len(names) == 0 {
+			if names, err = gobump.ListCampaigns(cfg.StateDir); err != nil {
+				return err
+			}
+		}
+
+		statuses := map[string][]gobump.PullRequest{}
+		for _, name := range names {
+			c, err := gobump.OpenCampaign(cfg.StateDir, name)
+			if err != nil {
+				return err
+			}
+
+			if statuses[name], err = c.PullRequests(context.Background()); err != nil {
+				return err
+			}
+		}
+
+		if output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(statuses)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CAMPAIGN\tREPO\tSTATE\tCHECKS\tREVIEW\tCONFLICTS\tURL\tERROR")
+		for _, name := range names {
+			for _, pr := range statuses[name] {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%v\t%s\t%s\n", name, pr.Repo, pr.State, pr.Checks, pr.Review, pr.Conflicts, pr.URL, pr.Error)
+			}
+		}
+		return tw.Flush()
+	},
+}
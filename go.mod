@@ -1,8 +1,15 @@
 module github.com/jkonarze/gobump
 
-go 1.14
+go 1.21
 
 require (
 	github.com/gammazero/workerpool v0.0.0-20200311205957-7b00833861c6
 	github.com/spf13/cobra v1.10.2
+	go.yaml.in/yaml/v3 v3.0.4
+)
+
+require (
+	github.com/gammazero/deque v0.0.0-20200227231300-1e9af0e52b46 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.9 // indirect
 )
@@ -0,0 +1,288 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHosts are the hosts of the providers when a repository or an
+// organization doesn't name one.
+var defaultHosts = map[string]string{
+	"hub":    "github.com",
+	"github": "github.com",
+	"gitlab": "gitlab.com",
+}
+
+// apiTransport sends a request to the REST API of a host, path is relative
+// to the API root. An error means no response was received.
+type apiTransport interface {
+	do(ctx context.Context, host, method, path string, body []byte) (*apiResponse, error)
+}
+
+type apiResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// hubAPI goes through `hub api` and so reuses hub's credentials.
+type hubAPI struct{}
+
+func (hubAPI) do(ctx context.Context, host, method, path string, body []byte) (*apiResponse, error) {
+	cmd := exec.CommandContext(ctx, "hub", "api", "--include", "-X", method, path)
+	if host != defaultHosts["hub"] {
+		cmd.Env = append(os.Environ(), "GITHUB_HOST="+host)
+	}
+	if body != nil {
+		cmd.Args = append(cmd.Args, "--input", "-")
+		cmd.Stdin = bytes.NewReader(body)
+	}
+
+	// hub fails on error statuses but still prints the response
+	stdout, err := output(ctx, cmd)
+	if resp, perr := parseResponse(stdout); perr == nil {
+		return resp, nil
+	}
+	if err != nil {
+		// without a response only a failing network is worth retrying, not
+		// missing credentials or a missing hub
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			return nil, fmt.Errorf("hub api %s %s: %v", method, path, err)
+		}
+		return nil, gitFailure(fmt.Errorf("hub api %s %s: %v", method, path, err), []byte(err.Error()))
+	}
+
+	return nil, fmt.Errorf("hub api %s %s: unexpected output\n%s", method, path, stdout)
+}
+
+// parseResponse parses the status line, headers and body printed by
+// `hub api --include`.
+func parseResponse(raw []byte) (*apiResponse, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw)))
+	line, err := r.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		return nil, fmt.Errorf("not a status line: %q", line)
+	}
+	status, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(r.R)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiResponse{status: status, header: http.Header(header), body: body}, nil
+}
+
+// restAPI calls the API of the GitHub or GitLab host over HTTP with the
+// host's token.
+type restAPI struct {
+	provider string
+	hosts    map[string]Host
+	creds    *credentials
+	client   *http.Client
+}
+
+func newRestAPI(provider string, hosts map[string]Host) restAPI {
+	return restAPI{
+		provider: provider,
+		hosts:    hosts,
+		creds:    newCredentials(provider, hosts),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// url is the API URL of the path on the host, GitHub Enterprise serves
+// GraphQL next to its REST API rather than below it.
+func (r restAPI) url(host, path string) string {
+	base := r.hosts[host].APIURL
+	switch {
+	case base != "":
+	case r.provider == "gitlab":
+		base = "https://" + host + "/api/v4"
+	case host == defaultHosts["github"]:
+		base = "https://api.github.com"
+	default:
+		base = "https://" + host + "/api/v3"
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	if path == "graphql" && strings.HasSuffix(base, "/v3") {
+		return strings.TrimSuffix(base, "/v3") + "/graphql"
+	}
+
+	return base + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (r restAPI) do(ctx context.Context, host, method, path string, body []byte) (*apiResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.url(host, path), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.provider == "github" {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := r.creds.token(ctx, host); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// the network failed, the request may be tried again
+		return nil, transient(err, 0)
+	}
+	defer resp.Body.Close()
+
+	read, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, transient(err, 0)
+	}
+
+	return &apiResponse{status: resp.StatusCode, header: resp.Header, body: read}, nil
+}
+
+// apiError is an error status returned by the API.
+type apiError struct {
+	method string
+	path   string
+	status int
+	body   []byte
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s\n%s", e.method, e.path, e.status, http.StatusText(e.status), e.body)
+}
+
+func isNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// hasStatus tells whether err is one of the error statuses.
+func hasStatus(err error, statuses ...int) bool {
+	var e *apiError
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	for _, status := range statuses {
+		if e.status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apiClient sends the API calls of a provider through the shared rate
+// limiter, retries transient failures and decodes the responses.
+type apiClient struct {
+	api     apiTransport
+	retry   Retry
+	limiter *rateLimiter
+}
+
+func newAPIClient(api apiTransport, cfg Retry) apiClient {
+	return apiClient{api: api, retry: cfg, limiter: newRateLimiter(cfg.RateLimit)}
+}
+
+// call sends a request and decodes the response into out.
+func (c apiClient) call(ctx context.Context, host, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	var resp *apiResponse
+	err := retry(ctx, c.retry, method+" "+path, func() error {
+		if err := c.limiter.wait(ctx, method != http.MethodGet); err != nil {
+			return err
+		}
+
+		var err error
+		resp, err = c.api.do(ctx, host, method, path, body)
+		logAPICall(ctx, host, method, path, body, resp, err)
+		if err != nil {
+			// a POST, such as opening a pull request or a fork, may have gone
+			// through before the network failed and mustn't be sent twice
+			if method == http.MethodPost && !notSent(err) {
+				return permanent(err)
+			}
+			return err
+		}
+
+		wait := rateLimitWait(resp.header)
+		if resp.status < 300 {
+			// the limit is exhausted by this call, hold the next ones back
+			if wait > 0 {
+				c.limiter.pause(wait)
+			}
+			return nil
+		}
+
+		err = &apiError{method: method, path: path, status: resp.status, body: resp.body}
+		switch {
+		case resp.status == http.StatusTooManyRequests, resp.status >= 500 && method != http.MethodPost,
+			resp.status == http.StatusForbidden && (wait > 0 || bytes.Contains(bytes.ToLower(resp.body), []byte("rate limit"))):
+			c.limiter.pause(wait)
+			return transient(err, wait)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if out == nil || len(bytes.TrimSpace(resp.body)) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(resp.body, out)
+}
+
+// splitOrg takes the host off `host/org`, an organization without one is
+// on the provider's default host.
+func splitOrg(provider, org string) (host, name string) {
+	if i := strings.Index(org, "/"); i != -1 && strings.Contains(org[:i], ".") {
+		return org[:i], org[i+1:]
+	}
+
+	return defaultHosts[provider], org
+}
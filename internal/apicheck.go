@@ -0,0 +1,302 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// apiViolation is a use of a standard library symbol which was introduced
+// after the version being checked against.
+type apiViolation struct {
+	pos     token.Position
+	symbol  string
+	version string
+}
+
+func (v apiViolation) String() string {
+	return fmt.Sprintf("%s: %s requires go %s", v.pos, v.symbol, v.version)
+}
+
+// stdAPI maps `pkg.Name` and `pkg.Type.Member` to the go version which
+// introduced the symbol, as listed in $GOROOT/api/go1.*.txt.
+type stdAPI map[string]string
+
+func loadStdAPI() (stdAPI, error) {
+	output, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(strings.TrimSpace(string(output)), "api", "go1*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no api files found in GOROOT")
+	}
+
+	api := stdAPI{}
+	for _, name := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), "go"), ".txt")
+		if version == "1" {
+			version = "1.0"
+		}
+
+		if err := api.read(name, version); err != nil {
+			return nil, err
+		}
+	}
+
+	return api, nil
+}
+
+func (a stdAPI) read(name, version string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key := apiKey(scanner.Text())
+		if key == "" {
+			continue
+		}
+		if existing, ok := a[key]; !ok || compareVersions(version, existing) < 0 {
+			a[key] = version
+		}
+	}
+
+	return scanner.Err()
+}
+
+// apiKey extracts the symbol from lines such as
+// `pkg bytes, method (*Buffer) Available() int #53685`.
+func apiKey(line string) string {
+	if !strings.HasPrefix(line, "pkg ") || strings.Contains(line, "//deprecated") {
+		return ""
+	}
+
+	comma := strings.Index(line, ", ")
+	if comma == -1 {
+		return ""
+	}
+	pkg := strings.Fields(line[len("pkg "):comma])[0]
+	decl := strings.Fields(line[comma+2:])
+	if len(decl) < 2 {
+		return ""
+	}
+
+	switch decl[0] {
+	case "func", "var", "const":
+		return pkg + "." + identPrefix(decl[1])
+	case "method":
+		if len(decl) < 3 {
+			return ""
+		}
+		recv := strings.Trim(decl[1], "(*)")
+		return pkg + "." + identPrefix(recv) + "." + identPrefix(decl[2])
+	case "type":
+		rest := strings.TrimSpace(line[comma+2+len("type "):])
+		name := identPrefix(rest)
+		rest = strings.TrimPrefix(rest, name)
+		if strings.HasPrefix(rest, "[") {
+			rest = rest[strings.Index(rest, "]")+1:]
+		}
+		// `type T struct, Field int` and `type T interface, M()` list members
+		fields := strings.Fields(rest)
+		if len(fields) > 1 && (fields[0] == "struct," || fields[0] == "interface,") {
+			member := fields[1]
+			if member == "embedded" && len(fields) > 2 {
+				member = strings.TrimPrefix(fields[2], "*")
+				member = member[strings.LastIndex(member, ".")+1:]
+			}
+			return pkg + "." + name + "." + identPrefix(member)
+		}
+		return pkg + "." + name
+	}
+
+	return ""
+}
+
+func identPrefix(s string) string {
+	if i := strings.IndexAny(s, "[(, "); i != -1 {
+		return s[:i]
+	}
+	return s
+}
+
+// checkAPI type-checks every package of the repository and reports the
+// standard library symbols newer than the target version.
+func checkAPI(root, target string, api stdAPI) ([]apiViolation, error) {
+	dirs := map[string][]string{}
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() && (fi.Name() == "vendor" || fi.Name() == "testdata" || strings.HasPrefix(fi.Name(), ".")) && path != root {
+			return filepath.SkipDir
+		}
+
+		if !fi.IsDir() && filepath.Ext(path) == ".go" {
+			dirs[filepath.Dir(path)] = append(dirs[filepath.Dir(path)], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	imp := &stdImporter{gc: importer.ForCompiler(fset, "gc", nil)}
+
+	var violations []apiViolation
+	for _, files := range dirs {
+		packages := map[string][]*ast.File{}
+		for _, name := range files {
+			file, err := parser.ParseFile(fset, name, nil, 0)
+			if err != nil {
+				continue
+			}
+			packages[file.Name.Name] = append(packages[file.Name.Name], file)
+		}
+
+		for name, parsed := range packages {
+			info := &types.Info{
+				Uses:       map[*ast.Ident]types.Object{},
+				Selections: map[*ast.SelectorExpr]*types.Selection{},
+			}
+			conf := types.Config{Importer: imp, Error: func(error) {}}
+			_, _ = conf.Check(name, fset, parsed, info)
+
+			violations = append(violations, api.violations(fset, info, target)...)
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].pos.Filename != violations[j].pos.Filename {
+			return violations[i].pos.Filename < violations[j].pos.Filename
+		}
+		return violations[i].pos.Offset < violations[j].pos.Offset
+	})
+
+	return violations, nil
+}
+
+func (a stdAPI) violations(fset *token.FileSet, info *types.Info, target string) []apiViolation {
+	var violations []apiViolation
+	report := func(pos token.Pos, key string) {
+		if version, ok := a[key]; ok && compareVersions(version, target) > 0 {
+			violations = append(violations, apiViolation{pos: fset.Position(pos), symbol: key, version: version})
+		}
+	}
+
+	for sel, selection := range info.Selections {
+		obj := selection.Obj()
+		if obj.Pkg() == nil || len(selection.Index()) != 1 {
+			continue
+		}
+		if named := namedOf(selection.Recv()); named != nil {
+			report(sel.Sel.Pos(), obj.Pkg().Path()+"."+named.Obj().Name()+"."+obj.Name())
+		}
+	}
+
+	for ident, obj := range info.Uses {
+		if obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+			continue
+		}
+		report(ident.Pos(), obj.Pkg().Path()+"."+obj.Name())
+	}
+
+	return violations
+}
+
+func namedOf(t types.Type) *types.Named {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, _ := t.(*types.Named)
+	return named
+}
+
+// stdImporter imports the standard library from export data and stands in
+// empty packages for everything else, which is enough to resolve the
+// standard library symbols used by the code.
+type stdImporter struct {
+	gc types.Importer
+}
+
+func (i *stdImporter) Import(path string) (*types.Package, error) {
+	if pkg, err := i.gc.Import(path); err == nil {
+		return pkg, nil
+	}
+
+	pkg := types.NewPackage(path, path[strings.LastIndex(path, "/")+1:])
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+type apiLoader struct {
+	once sync.Once
+	api  stdAPI
+	err  error
+}
+
+func (l *apiLoader) load() (stdAPI, error) {
+	l.once.Do(func() {
+		l.api, l.err = loadStdAPI()
+	})
+
+	return l.api, l.err
+}
+
+// checkDowngrade blocks a bump to a lower version than the repository uses
+// when the code relies on standard library symbols the target doesn't have.
+func (w *Worker) checkDowngrade(path string) error {
+	read, err := ioutil.ReadFile(filepath.Join(path, goMod))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	current := goDirective(read)
+	if current == "" || compareVersions(w.version, current) >= 0 {
+		return nil
+	}
+
+	api, err := w.api.load()
+	if err != nil {
+		return err
+	}
+
+	violations, err := checkAPI(path, w.version, api)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range violations {
+		w.log.Warn("uses api newer than the target", "to", w.version, "use", v.String())
+	}
+
+	if len(violations) > 0 && !w.cfg.Force {
+		return fmt.Errorf("%s uses %d symbols newer than go %s, use --force to bump anyway", path, len(violations), w.version)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAPIKey(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "pkg strings, func Cut(string, string) (string, string, bool)", want: "strings.Cut"},
+		{line: "pkg slices, func Contains[$0 interface{ ~[]$1 }, $1 comparable]($0, $1) bool", want: "slices.Contains"},
+		{line: "pkg bytes, method (*Buffer) AvailableBuffer() []uint8 #53685", want: "bytes.Buffer.AvailableBuffer"},
+		{line: "pkg time, method (Time) Compare(Time) int", want: "time.Time.Compare"},
+		{line: "pkg os, var ErrProcessDone error", want: "os.ErrProcessDone"},
+		{line: "pkg net/http, const StatusEarlyHints = 103", want: "net/http.StatusEarlyHints"},
+		{line: "pkg net/http, const StatusEarlyHints ideal-int", want: "net/http.StatusEarlyHints"},
+		{line: "pkg log/slog, type Attr struct", want: "log/slog.Attr"},
+		{line: "pkg log/slog, type Attr struct, Key string", want: "log/slog.Attr.Key"},
+		{line: "pkg io/fs, type FS interface, Open(string) (File, error)", want: "io/fs.FS.Open"},
+		{line: "pkg net/http, type Request struct, embedded context.Context", want: "net/http.Request.Context"},
+		{line: "pkg sync/atomic, type Pointer[$0 interface{}] struct", want: "sync/atomic.Pointer"},
+		{line: "pkg syscall (linux-386), func Setuid(int) error", want: "syscall.Setuid"},
+		{line: "pkg go/ast, func MergePackageFiles(*Package, MergeMode) *File //deprecated"},
+		{line: "pkg strings"},
+		{line: "pkg strings, method"},
+		{line: "# comment"},
+		{line: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := apiKey(tt.line); got != tt.want {
+				t.Errorf("apiKey(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAPI(t *testing.T) {
+	api := stdAPI{
+		"strings.Cut":                  "1.18",
+		"strings.Contains":             "1.0",
+		"bytes.Buffer.AvailableBuffer": "1.21",
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module x\n\ngo 1.21\n",
+		"a.go": `package x
+
+import (
+	"bytes"
+	"strings"
+)
+
+func f(b *bytes.Buffer, s string) bool {
+	_ = b.AvailableBuffer()
+	before, _, _ := strings.Cut(s, "=")
+	return strings.Contains(before, "x")
+}
+`,
+		"sub/b.go":          "package sub\n\nimport \"strings\"\n\nvar _, _, _ = strings.Cut(\"a=b\", \"=\")\n",
+		"vendor/v/v.go":     "package v\n\nimport \"strings\"\n\nvar _, _, _ = strings.Cut(\"a=b\", \"=\")\n",
+		"testdata/t.go":     "package t\n\nimport \"strings\"\n\nvar _, _, _ = strings.Cut(\"a=b\", \"=\")\n",
+		"broken/broken.go":  "package broken\n\nfunc f( {\n",
+		".hidden/hidden.go": "package hidden\n\nimport \"strings\"\n\nvar _, _, _ = strings.Cut(\"a=b\", \"=\")\n",
+	})
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{target: "1.17", want: []string{"bytes.Buffer.AvailableBuffer", "strings.Cut", "strings.Cut"}},
+		{target: "1.20", want: []string{"bytes.Buffer.AvailableBuffer"}},
+		{target: "1.21"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			violations, err := checkAPI(dir, tt.target, api)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.symbol)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkAPI(%s) = %v, want %v", tt.target, violations, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	stageDiscovered = "discovered"
+	stageEdited     = "edited"
+	stageVerified   = "verified"
+	stageCommitted  = "committed"
+	stagePushed     = "pushed"
+	stagePR         = "pr"
+
+	statusPending = "pending"
+	statusDone    = "done"
+	statusSkipped = "skipped"
+	statusFailed  = "failed"
+)
+
+// Campaign persists the progress of a bump across many repositories so an
+// interrupted run can be resumed.
+type Campaign struct {
+	Name    string                `json:"name"`
+	Path    string                `json:"path,omitempty"`
+	Remotes []string              `json:"remotes,omitempty"`
+	Config  Config                `json:"config"`
+	Repos   map[string]*RepoState `json:"repos"`
+
+	file string
+	mu   sync.Mutex
+}
+
+type RepoState struct {
+	Stage  string      `json:"stage"`
+	Status string      `json:"status"`
+	From   string      `json:"from,omitempty"`
+	To     string      `json:"to,omitempty"`
+	Deps   []DepChange `json:"deps,omitempty"`
+	PR     string      `json:"pr,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+func DefaultStateDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(dir, configDir)
+}
+
+func campaignFile(stateDir, name string) string {
+	if stateDir == "" {
+		stateDir = DefaultStateDir()
+	}
+
+	return filepath.Join(stateDir, "campaigns", name+".json")
+}
+
+// NewCampaign starts a campaign, refusing to overwrite an existing one.
+func NewCampaign(stateDir, name, path string, remotes []string, cfg Config) (*Campaign, error) {
+	file := campaignFile(stateDir, name)
+	if _, err := os.Stat(file); err == nil {
+		return nil, fmt.Errorf("campaign %s already exists, use `gobump resume %s`", name, name)
+	}
+
+	c := &Campaign{
+		Name:    name,
+		Path:    path,
+		Remotes: remotes,
+		Config:  cfg,
+		Repos:   map[string]*RepoState{},
+		file:    file,
+	}
+
+	return c, c.save()
+}
+
+func OpenCampaign(stateDir, name string) (*Campaign, error) {
+	file := campaignFile(stateDir, name)
+	read, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no campaign named %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Campaign{file: file}
+	if err := json.Unmarshal(read, c); err != nil {
+		return nil, fmt.Errorf("campaign %s: %v", name, err)
+	}
+	if c.Repos == nil {
+		c.Repos = map[string]*RepoState{}
+	}
+
+	return c, nil
+}
+
+func (c *Campaign) save() error {
+	read, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return err
+	}
+
+	tmp := c.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, read, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.file)
+}
+
+// record changes the state of a repository and saves the campaign. Every
+// method is a no-op on a nil campaign so the worker can call them freely.
+func (c *Campaign) record(name string, change func(*RepoState)) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.Repos[name]
+	if !ok {
+		state = &RepoState{}
+		c.Repos[name] = state
+	}
+	change(state)
+
+	if err := c.save(); err != nil {
+		slog.Error("saving campaign", "campaign", c.Name, "err", err)
+	}
+}
+
+func (c *Campaign) state(name string) RepoState {
+	if c == nil {
+		return RepoState{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if state, ok := c.Repos[name]; ok {
+		return *state
+	}
+
+	return RepoState{}
+}
+
+func (c *Campaign) completed(name string) bool {
+	status := c.state(name).Status
+	return status == statusDone || status == statusSkipped
+}
+
+func (c *Campaign) discover(name string) {
+	c.record(name, func(state *RepoState) {
+		if state.Stage == "" {
+			state.Stage = stageDiscovered
+		}
+		state.Status = statusPending
+		state.Error = ""
+	})
+}
+
+func (c *Campaign) update(name, stage string) {
+	c.record(name, func(state *RepoState) {
+		state.Stage = stage
+	})
+}
+
+func (c *Campaign) edited(name, from, to string, deps []DepChange) {
+	c.record(name, func(state *RepoState) {
+		state.Stage = stageEdited
+		// a retried bump finds go.mod already edited
+		if state.From == "" {
+			state.From = from
+		}
+		state.To = to
+		state.Deps = deps
+	})
+}
+
+func (c *Campaign) opened(name, url string) {
+	c.record(name, func(state *RepoState) {
+		state.Stage = stagePR
+		state.Status = statusDone
+		state.PR = url
+	})
+}
+
+func (c *Campaign) skip(name, reason string) {
+	c.record(name, func(state *RepoState) {
+		state.Status = statusSkipped
+		state.Error = reason
+	})
+}
+
+// postpone leaves a repository pending, noting why it wasn't finished.
+func (c *Campaign) postpone(name, reason string) {
+	c.record(name, func(state *RepoState) {
+		if state.Stage == "" {
+			state.Stage = stageDiscovered
+		}
+		state.Status = statusPending
+		state.Error = reason
+	})
+}
+
+func (c *Campaign) fail(name string, err error) {
+	c.record(name, func(state *RepoState) {
+		state.Status = statusFailed
+		state.Error = Redact(err.Error())
+	})
+}
+
+// ListCampaigns returns the names of the campaigns in the state directory.
+func ListCampaigns(stateDir string) ([]string, error) {
+	files, err := filepath.Glob(campaignFile(stateDir, "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// PullRequests queries the provider for every pull request the campaign
+// opened. A pull request which can't be queried has its Error set, the
+// others are still queried.
+func (c *Campaign) PullRequests(ctx context.Context) ([]PullRequest, error) {
+	p, err := newProvider(c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name, state := range c.Repos {
+		if state.PR != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var prs []PullRequest
+	for _, name := range names {
+		pr, err := p.PullRequest(ctx, c.Repos[name].PR)
+		if err != nil {
+			pr.URL, pr.Error = c.Repos[name].PR, Redact(err.Error())
+		}
+		pr.Repo = name
+		prs = append(prs, pr)
+	}
+
+	return prs, nil
+}
+
+// Merge merges every pull request of the campaign which is ready, see
+// PullRequest.Blocker, with the merge, squash or rebase method. The merged
+// pull requests are returned as merged, the others as they are; a failed
+// merge is recorded as the pull request's error and the others still merge.
+func (c *Campaign) Merge(ctx context.Context, method string) ([]PullRequest, error) {
+	if err := validateMergeMethod(method); err != nil {
+		return nil, err
+	}
+
+	p, err := newProvider(c.Config)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := p.(Merger)
+	if !ok {
+		return nil, fmt.Errorf("provider %s can't merge pull requests", c.Config.Provider)
+	}
+
+	prs, err := c.PullRequests(ctx)
+	if err != nil {
+		return prs, err
+	}
+
+	for i, pr := range prs {
+		if pr.Blocker() != "" {
+			continue
+		}
+
+		if err := m.Merge(ctx, pr.URL, method); err != nil {
+			prs[i].Error = Redact(err.Error())
+			slog.Warn("merge failed", "campaign", c.Name, "repo", pr.Repo, "pr", pr.URL, "err", err)
+			continue
+		}
+		prs[i].State = prMerged
+		slog.Info("merged", "campaign", c.Name, "repo", pr.Repo, "pr", pr.URL)
+	}
+
+	return prs, nil
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCampaignTransitions(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(c *Campaign)
+		state     RepoState
+		completed bool
+	}{
+		{
+			name:   "discovered",
+			change: func(c *Campaign) { c.discover("a") },
+			state:  RepoState{Stage: stageDiscovered, Status: statusPending},
+		},
+		{
+			name: "opened",
+			change: func(c *Campaign) {
+				c.discover("a")
+				c.edited("a", "1.21", "1.22", nil)
+				c.update("a", stagePushed)
+				c.opened("a", "https://github.com/acme/a/pull/1")
+			},
+			state:     RepoState{Stage: stagePR, Status: statusDone, From: "1.21", To: "1.22", PR: "https://github.com/acme/a/pull/1"},
+			completed: true,
+		},
+		{
+			name:      "skipped",
+			change:    func(c *Campaign) { c.skip("a", "already on 1.22") },
+			state:     RepoState{Status: statusSkipped, Error: "already on 1.22"},
+			completed: true,
+		},
+		{
+			name: "postponed in review",
+			change: func(c *Campaign) {
+				c.discover("a")
+				c.edited("a", "1.21", "1.22", nil)
+				c.postpone("a", "skipped in review")
+			},
+			state: RepoState{Stage: stageEdited, Status: statusPending, From: "1.21", To: "1.22", Error: "skipped in review"},
+		},
+		{
+			name:   "postponed before discovery",
+			change: func(c *Campaign) { c.postpone("a", "review quit") },
+			state:  RepoState{Stage: stageDiscovered, Status: statusPending, Error: "review quit"},
+		},
+		{
+			name: "failed",
+			change: func(c *Campaign) {
+				c.discover("a")
+				c.update("a", stageCommitted)
+				c.fail("a", errors.New("push rejected"))
+			},
+			state: RepoState{Stage: stageCommitted, Status: statusFailed, Error: "push rejected"},
+		},
+		{
+			name: "rediscovered after failing",
+			change: func(c *Campaign) {
+				c.discover("a")
+				c.edited("a", "1.21", "1.22", nil)
+				c.fail("a", errors.New("verification failed"))
+				c.discover("a")
+			},
+			state: RepoState{Stage: stageEdited, Status: statusPending, From: "1.21", To: "1.22"},
+		},
+		{
+			name: "edited again keeps the first version",
+			change: func(c *Campaign) {
+				c.edited("a", "1.21", "1.22", nil)
+				c.edited("a", "1.22", "1.22", nil)
+			},
+			state: RepoState{Stage: stageEdited, From: "1.21", To: "1.22"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c, err := NewCampaign(dir, "c", "", nil, Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(c)
+
+			// the state must survive a resume
+			c, err = OpenCampaign(dir, "c")
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := c.state("a")
+			if state.Stage != tt.state.Stage || state.Status != tt.state.Status || state.From != tt.state.From ||
+				state.To != tt.state.To || state.PR != tt.state.PR || state.Error != tt.state.Error {
+				t.Errorf("state = %+v, want %+v", state, tt.state)
+			}
+			if got := c.completed("a"); got != tt.completed {
+				t.Errorf("completed() = %v, want %v", got, tt.completed)
+			}
+		})
+	}
+}
+
+func TestNewCampaignExisting(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewCampaign(dir, "c", "", nil, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCampaign(dir, "c", "", nil, Config{}); err == nil {
+		t.Errorf("NewCampaign() overwrote an existing campaign")
+	}
+	if _, err := OpenCampaign(dir, "missing"); err == nil {
+		t.Errorf("OpenCampaign() opened a missing campaign")
+	}
+}
+
+func TestNilCampaign(t *testing.T) {
+	var c *Campaign
+	c.discover("a")
+	c.skip("a", "reason")
+	if c.completed("a") {
+		t.Errorf("nil campaign completed a repository")
+	}
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// UseRemotes makes the worker clone the given repositories into a temporary
+// workspace instead of bumping the checkouts found under its path. Two
+// different repositories sharing a name are refused.
+func (w *Worker) UseRemotes(urls []string) error {
+	w.remotes = map[string]string{}
+	for _, url := range urls {
+		name := remoteName(url)
+		if other, ok := w.remotes[name]; ok && other != url {
+			return fmt.Errorf("%s and %s are both named %s", other, url, name)
+		}
+		w.remotes[name] = url
+	}
+
+	return nil
+}
+
+// ReadRepoList reads one repository per line, either a clone URL, a local
+// path or an `owner/name` on GitHub. Relative paths start with `./` or `../`
+// and are relative to the list. Blank lines and # comments are ignored.
+func ReadRepoList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var urls []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if isRelative(line) {
+			abs, err := filepath.Abs(filepath.Join(filepath.Dir(path), line))
+			if err != nil {
+				return nil, err
+			}
+			line = abs
+		} else if !strings.Contains(line, "://") && !strings.HasPrefix(line, "git@") && !filepath.IsAbs(line) {
+			line = "https://github.com/" + strings.TrimSuffix(line, ".git") + ".git"
+		}
+		urls = append(urls, line)
+	}
+
+	return urls, scanner.Err()
+}
+
+func isRelative(path string) bool {
+	path = filepath.ToSlash(path)
+	return path == "." || path == ".." || strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
+}
+
+// OrgRepos asks the provider for every Go repository of an organization.
+func OrgRepos(ctx context.Context, cfg Config, org string) ([]string, error) {
+	p, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Repositories(ctx, org)
+}
+
+// remoteName turns `https://github.com/owner/name.git` or
+// `git@github.com:owner/name.git` into `owner/name`, keeping GitLab
+// subgroups. Local paths are named by their last two directories.
+func remoteName(url string) string {
+	parts := strings.Split(remotePath(url), "/")
+	local := filepath.IsAbs(url) || isRelative(url) || strings.HasPrefix(url, "file://")
+	if local && len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+
+	return filepath.Join(parts...)
+}
+
+// remotePath is the full path of the repository on its host, which has
+// subgroups on GitLab: `group/sub/name`.
+func remotePath(url string) string {
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	if i := strings.Index(url, "://"); i != -1 {
+		url = url[i+3:]
+		url = url[strings.Index(url, "/")+1:]
+	} else if i := strings.Index(url, ":"); i != -1 {
+		url = url[i+1:]
+	}
+
+	return url
+}
+
+// remoteHost is the host name of a remote URL, without user and port.
+func remoteHost(url string) string {
+	if i := strings.Index(url, "://"); i != -1 {
+		url = url[i+3:]
+		if i := strings.Index(url, "/"); i != -1 {
+			url = url[:i]
+		}
+	} else if i := strings.Index(url, ":"); i != -1 {
+		url = url[:i]
+	}
+
+	if i := strings.LastIndex(url, "@"); i != -1 {
+		url = url[i+1:]
+	}
+	if i := strings.Index(url, ":"); i != -1 {
+		url = url[:i]
+	}
+
+	return url
+}
+
+func clone(ctx context.Context, url, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--no-single-branch", url, path)
+	if output, err := run(ctx, cmd); err != nil {
+		return gitFailure(fmt.Errorf("clone %s: %v\n%s", url, err, output), output)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRemoteName(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://github.com/acme/a.git", want: "acme/a"},
+		{url: "git@github.com:acme/a.git", want: "acme/a"},
+		{url: "https://gitlab.com/acme/sub/a.git", want: "acme/sub/a"},
+		{url: "git@gitlab.com:acme/other/a", want: "acme/other/a"},
+		{url: "ssh://git@gitlab.example.com:2222/acme/sub/a.git", want: "acme/sub/a"},
+		{url: "/srv/git/acme/a", want: "acme/a"},
+		{url: "file:///srv/git/acme/a.git", want: "acme/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := remoteName(tt.url); got != filepath.FromSlash(tt.want) {
+				t.Errorf("remoteName(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseRemotes(t *testing.T) {
+	tests := []struct {
+		name  string
+		urls  []string
+		fails bool
+	}{
+		{
+			name: "subgroups",
+			urls: []string{"git@gitlab.com:acme/sub/a.git", "git@gitlab.com:acme/other/a.git"},
+		},
+		{
+			name: "listed twice",
+			urls: []string{"https://github.com/acme/a.git", "https://github.com/acme/a.git"},
+		},
+		{
+			name:  "same path on two hosts",
+			urls:  []string{"https://github.com/acme/a.git", "https://gitlab.com/acme/a.git"},
+			fails: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWorker(t.TempDir(), Config{})
+			if err := w.UseRemotes(tt.urls); (err != nil) != tt.fails {
+				t.Errorf("UseRemotes() = %v, want failure %v", err, tt.fails)
+			}
+		})
+	}
+}
+
+func TestReadRepoList(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"lists/repos.txt": `# repositories
+acme/a
+acme/b.git
+
+https://gitlab.com/acme/sub/c.git
+git@github.com:acme/d.git
+/srv/git/e
+./local/f
+../g
+`})
+
+	got, err := ReadRepoList(filepath.Join(dir, "lists", "repos.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"https://github.com/acme/a.git",
+		"https://github.com/acme/b.git",
+		"https://gitlab.com/acme/sub/c.git",
+		"git@github.com:acme/d.git",
+		"/srv/git/e",
+		filepath.Join(dir, "lists", "local", "f"),
+		filepath.Join(dir, "g"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadRepoList() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// codeOwnersFiles are the places GitHub looks for CODEOWNERS, in order.
+var codeOwnersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeOwners reads the CODEOWNERS rules of a checkout, nil when it has none.
+func codeOwners(dir string) ([]codeOwnersRule, error) {
+	for _, name := range codeOwnersFiles {
+		read, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return parseCodeOwners(read), nil
+	}
+
+	return nil, nil
+}
+
+func parseCodeOwners(content []byte) []codeOwnersRule {
+	var rules []codeOwnersRule
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		pattern, err := regexp.Compile(codeOwnersPattern(fields[0]))
+		if err != nil {
+			continue
+		}
+		rules = append(rules, codeOwnersRule{pattern: pattern, owners: fields[1:]})
+	}
+
+	return rules
+}
+
+// codeOwnersPattern translates a gitignore style pattern to a regexp
+// matching slash separated paths relative to the repository root.
+func codeOwnersPattern(pattern string) string {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	// a slash other than a trailing one anchors the pattern at the root
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		b.WriteString("(.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(/.*)?$")
+	}
+
+	return b.String()
+}
+
+// owners returns the users and the team slugs owning the files, the last
+// matching rule of a file wins. Owners given by email are left out as
+// they can't be requested by login.
+func owners(rules []codeOwnersRule, files []string) (users, teams []string) {
+	seen := map[string]bool{}
+	for _, f := range files {
+		var matched []string
+		for _, rule := range rules {
+			if rule.pattern.MatchString(f) {
+				matched = rule.owners
+			}
+		}
+
+		for _, owner := range matched {
+			if !strings.HasPrefix(owner, "@") || seen[owner] {
+				continue
+			}
+			seen[owner] = true
+
+			owner = strings.TrimPrefix(owner, "@")
+			if i := strings.Index(owner, "/"); i != -1 {
+				teams = append(teams, owner[i+1:])
+			} else {
+				users = append(users, owner)
+			}
+		}
+	}
+
+	sort.Strings(users)
+	sort.Strings(teams)
+	return users, teams
+}
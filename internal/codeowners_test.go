@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestCodeOwnersPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{
+			pattern: "*",
+			match:   []string{"go.mod", "cmd/root.go"},
+		},
+		{
+			pattern: "*.go",
+			match:   []string{"main.go", "internal/worker.go"},
+			noMatch: []string{"go.mod", "main.go.orig"},
+		},
+		{
+			pattern: "go.mod",
+			match:   []string{"go.mod", "tools/go.mod"},
+			noMatch: []string{"go.mod.bak", "xgo.mod"},
+		},
+		{
+			pattern: "/go.mod",
+			match:   []string{"go.mod"},
+			noMatch: []string{"tools/go.mod"},
+		},
+		{
+			pattern: "docs/",
+			match:   []string{"docs/README.md", "sub/docs/a/b.md"},
+			noMatch: []string{"docs", "documents/a.md"},
+		},
+		{
+			pattern: ".github/workflows/",
+			match:   []string{".github/workflows/ci.yaml"},
+			noMatch: []string{"sub/.github/workflows/ci.yaml"},
+		},
+		{
+			pattern: "apps/*/go.mod",
+			match:   []string{"apps/api/go.mod"},
+			noMatch: []string{"apps/api/v2/go.mod", "x/apps/api/go.mod"},
+		},
+		{
+			pattern: "**/deploy/*.yaml",
+			match:   []string{"deploy/app.yaml", "k8s/prod/deploy/app.yaml"},
+			noMatch: []string{"deploy/prod/app.yaml"},
+		},
+		{
+			pattern: "internal/**",
+			match:   []string{"internal/a.go", "internal/x/y/z.go"},
+			noMatch: []string{"cmd/internal/a.go"},
+		},
+		{
+			pattern: "v?.txt",
+			match:   []string{"v1.txt"},
+			noMatch: []string{"v12.txt", "v/.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re := regexp.MustCompile(codeOwnersPattern(tt.pattern))
+			for _, path := range tt.match {
+				if !re.MatchString(path) {
+					t.Errorf("%q (%s) doesn't match %q", tt.pattern, re, path)
+				}
+			}
+			for _, path := range tt.noMatch {
+				if re.MatchString(path) {
+					t.Errorf("%q (%s) matches %q", tt.pattern, re, path)
+				}
+			}
+		})
+	}
+}
+
+func TestOwners(t *testing.T) {
+	rules := parseCodeOwners([]byte(`# default owners
+*                   @acme/platform
+*.yaml              @acme/ci @alice
+/go.mod             @bob dev@example.com # email owners can't be requested
+docs/               @carol
+/vendor/            # no owners
+`))
+
+	tests := []struct {
+		name  string
+		files []string
+		users []string
+		teams []string
+	}{
+		{
+			name:  "default rule",
+			files: []string{"main.go"},
+			teams: []string{"platform"},
+		},
+		{
+			name:  "last matching rule wins",
+			files: []string{".github/workflows/ci.yaml"},
+			users: []string{"alice"},
+			teams: []string{"ci"},
+		},
+		{
+			name:  "email owners are left out",
+			files: []string{"go.mod"},
+			users: []string{"bob"},
+		},
+		{
+			name:  "owners of every file, once",
+			files: []string{"go.mod", "ci.yaml", "deploy.yaml", "docs/a.md"},
+			users: []string{"alice", "bob", "carol"},
+			teams: []string{"ci"},
+		},
+		{
+			name:  "rule without owners",
+			files: []string{"vendor/modules.txt"},
+		},
+		{
+			name: "no files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, teams := owners(rules, tt.files)
+			if !reflect.DeepEqual(users, tt.users) {
+				t.Errorf("owners() users = %v, want %v", users, tt.users)
+			}
+			if !reflect.DeepEqual(teams, tt.teams) {
+				t.Errorf("owners() teams = %v, want %v", teams, tt.teams)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	configDir      = "gobump"
+	configFile     = "config.yaml"
+	repoConfigFile = ".gobump.yaml"
+)
+
+// Config holds the organisation wide defaults, usually read from
+// $XDG_CONFIG_HOME/gobump/config.yaml.
+type Config struct {
+	Version     string          `yaml:"version"`
+	IndexURL    string          `yaml:"index_url"`
+	IndexCache  string          `yaml:"index_cache"`
+	Provider    string          `yaml:"provider"`
+	Concurrency int             `yaml:"concurrency"`
+	StateDir    string          `yaml:"state_dir"`
+	Editors     []string        `yaml:"editors"`
+	Verify      []string        `yaml:"verify"`
+	Modernize   bool            `yaml:"modernize"`
+	Worktree    bool            `yaml:"worktree"`
+	Interactive bool            `yaml:"-"`
+	RespectDeps bool            `yaml:"respect_deps"`
+	Deps        Deps            `yaml:"deps"`
+	Hooks       Hooks           `yaml:"hooks"`
+	Log         Log             `yaml:"log"`
+	Commit      Commit          `yaml:"commit"`
+	PullRequest PROptions       `yaml:"pull_request"`
+	Retry       Retry           `yaml:"retry"`
+	Hosts       map[string]Host `yaml:"hosts"`
+	Fork        string          `yaml:"fork"`
+	Policies    []Policy        `yaml:"policies"`
+	Force       bool            `yaml:"-"`
+	Templates   Templates       `yaml:"templates"`
+}
+
+// Templates are text/template strings rendered with the repository name and
+// the from/to go versions. The body can also use the generated Description,
+// which an empty body defaults to.
+type Templates struct {
+	Branch string `yaml:"branch"`
+	Commit string `yaml:"commit"`
+	Title  string `yaml:"title"`
+	Body   string `yaml:"body"`
+}
+
+type templateData struct {
+	Repo        string
+	From        string
+	To          string
+	Deps        []DepChange
+	Description string
+}
+
+func render(text string, data templateData) (string, error) {
+	tpl, err := template.New("gobump").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Commit configures the identity and signature of the bump commits. Sign
+// is `gpg` or `ssh`, SigningKey the key id or the ssh key file, both
+// default to git's own config. Signoff adds a Signed-off-by trailer.
+type Commit struct {
+	Author     Identity `yaml:"author"`
+	Committer  Identity `yaml:"committer"`
+	Sign       string   `yaml:"sign"`
+	SigningKey string   `yaml:"signing_key"`
+	Signoff    bool     `yaml:"signoff"`
+}
+
+func (c Commit) validate() error {
+	switch c.Sign {
+	case "", signGPG, signSSH:
+		return nil
+	}
+
+	return fmt.Errorf("unknown signing format %q, use gpg or ssh", c.Sign)
+}
+
+// Identity is a git author or committer, empty fields are left to git.
+type Identity struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+}
+
+// ParseIdentity parses `Name <email>`.
+func ParseIdentity(s string) (Identity, error) {
+	open, close := strings.Index(s, "<"), strings.LastIndex(s, ">")
+	if open == -1 || close < open {
+		return Identity{}, fmt.Errorf("identity %q isn't `Name <email>`", s)
+	}
+
+	return Identity{Name: strings.TrimSpace(s[:open]), Email: strings.TrimSpace(s[open+1 : close])}, nil
+}
+
+// PROptions are the labels, reviewers and the like of the bump pull
+// requests. Reviewers and Assignees are user logins, TeamReviewers team
+// slugs of the organization, Milestone a milestone title or number. The
+// owners of the changed files in CODEOWNERS are requested as reviewers too,
+// unless IgnoreCodeOwners is set. AutoMerge, `merge`, `squash` or `rebase`,
+// enables auto-merge with that method once the pull request is opened.
+type PROptions struct {
+	Labels           []string `yaml:"labels"`
+	Reviewers        []string `yaml:"reviewers"`
+	TeamReviewers    []string `yaml:"team_reviewers"`
+	Assignees        []string `yaml:"assignees"`
+	Milestone        string   `yaml:"milestone"`
+	Draft            *bool    `yaml:"draft"`
+	IgnoreCodeOwners bool     `yaml:"ignore_code_owners"`
+	AutoMerge        string   `yaml:"auto_merge"`
+}
+
+func (o PROptions) validate() error {
+	if o.AutoMerge == "" {
+		return nil
+	}
+
+	return validateMergeMethod(o.AutoMerge)
+}
+
+// override returns the options with every field set in repo replacing the
+// global one.
+func (o PROptions) override(repo PROptions) PROptions {
+	if repo.Labels != nil {
+		o.Labels = repo.Labels
+	}
+	if repo.Reviewers != nil {
+		o.Reviewers = repo.Reviewers
+	}
+	if repo.TeamReviewers != nil {
+		o.TeamReviewers = repo.TeamReviewers
+	}
+	if repo.Assignees != nil {
+		o.Assignees = repo.Assignees
+	}
+	if repo.Milestone != "" {
+		o.Milestone = repo.Milestone
+	}
+	if repo.Draft != nil {
+		o.Draft = repo.Draft
+	}
+	if repo.IgnoreCodeOwners {
+		o.IgnoreCodeOwners = true
+	}
+	if repo.AutoMerge != "" {
+		o.AutoMerge = repo.AutoMerge
+	}
+
+	return o
+}
+
+// Deps configures the dependency update run alongside the bump. Mode is
+// `all` (go get -u), `patch` (go get -u=patch) or `modules` to update only
+// the listed module paths; an empty mode leaves dependencies alone.
+type Deps struct {
+	Mode    string   `yaml:"mode"`
+	Modules []string `yaml:"modules"`
+}
+
+// RepoConfig is the per-repository .gobump.yaml.
+type RepoConfig struct {
+	MaxVersion string   `yaml:"max_version"`
+	Skip       bool     `yaml:"skip"`
+	ExtraFiles []string `yaml:"extra_files"`
+	Verify     []string `yaml:"verify"`
+	Hooks      Hooks    `yaml:"hooks"`
+	// PullRequest overrides the global pull request options field by field.
+	PullRequest PROptions `yaml:"pull_request"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Version:     "stable",
+		IndexURL:    DefaultIndexURL,
+		Provider:    "hub",
+		Concurrency: 30,
+		Editors:     []string{"*.yaml"},
+		Verify:      []string{"go build -o /dev/null ./..."},
+		Templates: Templates{
+			Branch: "next-Go",
+			Commit: "bump go version with go-bump",
+			Title:  "update go version",
+			Body:   "{{.Description}}",
+		},
+		PullRequest: PROptions{
+			Labels: []string{"minor"},
+		},
+		Retry: Retry{
+			Attempts:  4,
+			Backoff:   time.Second,
+			RateLimit: 1,
+		},
+	}
+}
+
+func DefaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, configDir, configFile)
+}
+
+// LoadConfig reads the global config on top of the defaults. A missing file
+// is not an error unless the path was given explicitly.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	explicit := path != ""
+	if !explicit {
+		path = DefaultConfigPath()
+	}
+
+	read, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(read, &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+func LoadRepoConfig(path string) (RepoConfig, error) {
+	var cfg RepoConfig
+
+	read, err := ioutil.ReadFile(filepath.Join(path, repoConfigFile))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(read, &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
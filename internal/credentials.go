@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Host configures a GitHub Enterprise or self-hosted GitLab host, keyed by
+// its name in the config. APIURL defaults to https://<host>/api/v3 for
+// GitHub and https://<host>/api/v4 for GitLab, TokenEnv names an
+// environment variable holding the host's token.
+type Host struct {
+	APIURL   string `yaml:"api_url"`
+	TokenEnv string `yaml:"token_env"`
+}
+
+// tokenEnv are the environment variables read for the token of every host
+// of a provider, in order.
+var tokenEnv = map[string][]string{
+	"github": {"GITHUB_TOKEN", "GH_TOKEN"},
+	"gitlab": {"GITLAB_TOKEN"},
+}
+
+// credentials finds the API token of a host in the host's TokenEnv, the
+// provider's environment variables, ~/.netrc and finally `git credential
+// fill`. Found tokens are cached and redacted from the logs.
+type credentials struct {
+	provider string
+	hosts    map[string]Host
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func newCredentials(provider string, hosts map[string]Host) *credentials {
+	return &credentials{provider: provider, hosts: hosts, tokens: map[string]string{}}
+}
+
+func (c *credentials) token(ctx context.Context, host string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if token, ok := c.tokens[host]; ok {
+		return token
+	}
+
+	token := c.lookup(ctx, host)
+	c.tokens[host] = token
+	addSecret(token)
+
+	return token
+}
+
+func (c *credentials) lookup(ctx context.Context, host string) string {
+	env := tokenEnv[c.provider]
+	if h, ok := c.hosts[host]; ok && h.TokenEnv != "" {
+		env = []string{h.TokenEnv}
+	}
+	for _, name := range env {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+
+	if token := netrcPassword(host); token != "" {
+		return token
+	}
+
+	return gitCredential(ctx, host)
+}
+
+// netrcPassword reads the password of the host's machine entry, or of the
+// default entry, from $NETRC or ~/.netrc.
+func netrcPassword(host string) string {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	read, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	var machine, fallback string
+	var current *string
+	fields := strings.Fields(string(read))
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(fields) && fields[i+1] == host {
+				current = &machine
+			}
+			i++
+		case "default":
+			current = &fallback
+		case "password":
+			if current != nil && i+1 < len(fields) {
+				*current = fields[i+1]
+			}
+			i++
+		case "login", "account", "macdef":
+			i++
+		}
+	}
+
+	if machine != "" {
+		return machine
+	}
+
+	return fallback
+}
+
+// gitCredential asks git's credential helpers for the host's password
+// without ever prompting. Its output isn't logged as it holds the secret.
+func gitCredential(ctx context.Context, host string) string {
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=https\nhost=%s\n\n", host))
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never", "GIT_ASKPASS=true")
+
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if password := strings.TrimPrefix(scanner.Text(), "password="); password != scanner.Text() {
+			return password
+		}
+	}
+
+	return ""
+}
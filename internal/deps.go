@@ -0,0 +1,350 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dependency is a required module together with the go version its own
+// go.mod declares.
+type dependency struct {
+	requirement
+	goVersion string
+}
+
+func (d dependency) String() string {
+	return fmt.Sprintf("%s@%s (go %s)", d.path, d.version, d.goVersion)
+}
+
+func modCache() (string, error) {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir, nil
+	}
+
+	output, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// dependencies reads the go directive of every required module from the
+// module cache, or from the directory a replace directive points to.
+// Modules which aren't in the cache are downloaded first, the ones which
+// can't be are returned as unchecked.
+func dependencies(ctx context.Context, dir string, mod []byte) ([]dependency, []requirement, error) {
+	cache, err := modCache()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reps := replaces(mod)
+	var deps []dependency
+	var missing, unchecked []requirement
+	for _, req := range requires(mod) {
+		r, replaced := replace(req, reps)
+		if replaced && r.newVersion != "" {
+			req = requirement{path: r.newPath, version: r.newVersion, indirect: req.indirect}
+		}
+		local := replaced && r.newVersion == ""
+
+		name := filepath.Join(cache, "cache", "download", escapeModulePath(req.path), "@v", escapeModulePath(req.version)+".mod")
+		if local {
+			name = r.newPath
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(dir, name)
+			}
+			name = filepath.Join(name, goMod)
+		}
+
+		read, err := ioutil.ReadFile(name)
+		switch {
+		case os.IsNotExist(err) && local:
+			unchecked = append(unchecked, req)
+			continue
+		case os.IsNotExist(err):
+			missing = append(missing, req)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if version := goDirective(read); version != "" {
+			deps = append(deps, dependency{requirement: req, goVersion: version})
+		}
+	}
+
+	downloaded, failed := download(ctx, missing)
+	return append(deps, downloaded...), append(unchecked, failed...), nil
+}
+
+// download fetches the go.mod files of modules missing from the module
+// cache and returns the modules it couldn't fetch separately. It runs
+// outside the repository, which planning must leave untouched, as the go
+// command would otherwise record the modules in its go.sum.
+func download(ctx context.Context, missing []requirement) ([]dependency, []requirement) {
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	dir, err := ioutil.TempDir("", "gobump-download")
+	if err != nil {
+		return nil, missing
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"mod", "download", "-json"}
+	byModule := map[string]requirement{}
+	for _, req := range missing {
+		args = append(args, req.path+"@"+req.version)
+		byModule[req.path+"@"+req.version] = req
+	}
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "GOTOOLCHAIN=local")
+	// a failing download still describes every module, with an Error
+	// for the ones it couldn't fetch
+	stdout, _ := output(ctx, cmd)
+
+	var deps []dependency
+	decoder := json.NewDecoder(bytes.NewReader(stdout))
+	for {
+		var module struct {
+			Path    string
+			Version string
+			GoMod   string
+			Error   string
+		}
+		if err := decoder.Decode(&module); err != nil {
+			break
+		}
+
+		req, ok := byModule[module.Path+"@"+module.Version]
+		if !ok || module.Error != "" || module.GoMod == "" {
+			continue
+		}
+		read, err := ioutil.ReadFile(module.GoMod)
+		if err != nil {
+			continue
+		}
+
+		delete(byModule, module.Path+"@"+module.Version)
+		if version := goDirective(read); version != "" {
+			deps = append(deps, dependency{requirement: req, goVersion: version})
+		}
+	}
+
+	var unchecked []requirement
+	for _, req := range missing {
+		if _, ok := byModule[req.path+"@"+req.version]; ok {
+			unchecked = append(unchecked, req)
+		}
+	}
+
+	return deps, unchecked
+}
+
+// checkDeps makes sure the target isn't lower than the go version required
+// by the dependencies, raising it with --respect-deps.
+func (w *Worker) checkDeps(ctx context.Context, path string) error {
+	read, err := ioutil.ReadFile(filepath.Join(path, goMod))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	deps, unchecked, err := dependencies(ctx, path, read)
+	if err != nil {
+		return err
+	}
+
+	for _, req := range unchecked {
+		w.log.Warn("dependency isn't in the module cache and couldn't be downloaded, its go version is unchecked",
+			"module", req.path+"@"+req.version)
+	}
+
+	minimum := ""
+	var offending []string
+	for _, dep := range deps {
+		if compareVersions(dep.goVersion, minimum) > 0 {
+			minimum = dep.goVersion
+		}
+		if compareVersions(dep.goVersion, w.version) > 0 {
+			offending = append(offending, dep.String())
+		}
+	}
+
+	if len(offending) == 0 {
+		return nil
+	}
+
+	if !w.cfg.RespectDeps {
+		return fmt.Errorf("%s: dependencies require go %s, higher than %s:\n\t%s",
+			path, minimum, w.version, strings.Join(offending, "\n\t"))
+	}
+
+	if w.repoCfg.MaxVersion != "" && compareVersions(minimum, w.repoCfg.MaxVersion) > 0 {
+		return fmt.Errorf("%s: dependencies require go %s, above max_version %s", path, minimum, w.repoCfg.MaxVersion)
+	}
+
+	w.log.Info("raising target to the go version required by dependencies", "to", minimum)
+	w.version = minimum
+	return nil
+}
+
+const (
+	depsAll     = "all"
+	depsPatch   = "patch"
+	depsModules = "modules"
+)
+
+// DepChange is a require directive changed by the dependency update, From
+// is empty for added modules and To for removed ones.
+type DepChange struct {
+	Path string
+	From string
+	To   string
+}
+
+// updateDeps refreshes the dependencies according to the deps mode and
+// records every require change.
+func (w *Worker) updateDeps(ctx context.Context, path string) error {
+	before, err := ioutil.ReadFile(filepath.Join(path, goMod))
+	if err != nil {
+		return err
+	}
+
+	commands, err := w.depsCommands()
+	if err != nil {
+		return err
+	}
+
+	for _, args := range commands {
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = filepath.Join(path)
+
+		if output, err := run(ctx, cmd); err != nil {
+			return fmt.Errorf("go %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	after, err := ioutil.ReadFile(filepath.Join(path, goMod))
+	if err != nil {
+		return err
+	}
+
+	w.depChanges = diffRequires(requires(before), requires(after))
+	return w.reconcileDirectives(path, before, after)
+}
+
+// depsCommands are the go commands of the dependency update, none without
+// a deps mode.
+func (w *Worker) depsCommands() ([][]string, error) {
+	var commands [][]string
+	switch w.cfg.Deps.Mode {
+	case "":
+		return nil, nil
+	case depsAll:
+		commands = append(commands, []string{"get", "-u", "./..."})
+	case depsPatch:
+		commands = append(commands, []string{"get", "-u=patch", "./..."})
+	case depsModules:
+		for _, module := range w.cfg.Deps.Modules {
+			if !strings.Contains(module, "@") {
+				module += "@latest"
+			}
+			commands = append(commands, []string{"get", module})
+		}
+	default:
+		return nil, fmt.Errorf("unknown deps mode %q", w.cfg.Deps.Mode)
+	}
+
+	return append(commands, []string{"mod", "tidy"}), nil
+}
+
+// reconcileDirectives undoes the go and toolchain changes the go command
+// made while updating the dependencies, so that go.mod keeps the target.
+// Dependencies which need a newer go than the target fail the bump.
+func (w *Worker) reconcileDirectives(path string, before, after []byte) error {
+	version, target := goDirective(after), w.version
+	// a dependency requiring the first release of the target's minor
+	// spells out `1.22` as `1.22.0`
+	if len(versionParts(target)) == 2 && version == target+".0" {
+		target = version
+	}
+	if compareVersions(version, target) > 0 {
+		return fmt.Errorf("%s: updated dependencies require go %s, higher than %s", path, version, w.version)
+	}
+
+	reconciled := after
+	if version != target {
+		reconciled = setGoDirective(reconciled, target)
+	}
+	if toolchain := toolchainDirective(before); toolchain != toolchainDirective(reconciled) {
+		reconciled = setToolchainDirective(reconciled, toolchain)
+	}
+
+	if bytes.Equal(reconciled, after) {
+		return nil
+	}
+
+	w.log.Info("reconciled go directives after the dependency update", "go", version, "to", target)
+	return ioutil.WriteFile(filepath.Join(path, goMod), reconciled, 0644)
+}
+
+func diffRequires(before, after []requirement) []DepChange {
+	old := map[string]string{}
+	for _, req := range before {
+		old[req.path] = req.version
+	}
+
+	var changes []DepChange
+	for _, req := range after {
+		if version, ok := old[req.path]; !ok || version != req.version {
+			changes = append(changes, DepChange{Path: req.path, From: version, To: req.version})
+		}
+		delete(old, req.path)
+	}
+
+	for _, req := range before {
+		if version, ok := old[req.path]; ok {
+			changes = append(changes, DepChange{Path: req.path, From: version})
+		}
+	}
+
+	return changes
+}
+
+// depsTable renders the dependency changes as a markdown table.
+func depsTable(changes []DepChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("| Module | From | To |\n|---|---|---|\n")
+	for _, c := range changes {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", c.Path, orDash(c.From), orDash(c.To))
+	}
+
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,258 @@
+package internal
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeProxy lays out a GOPROXY directory serving one module version
+// whose go.mod declares goVersion.
+func writeProxy(t *testing.T, dir, module, version, goVersion string) {
+	t.Helper()
+	mod := "module " + module + "\n\ngo " + goVersion + "\n"
+	base := filepath.Join(dir, filepath.FromSlash(escapeModulePath(module)), "@v")
+	writeFiles(t, base, map[string]string{
+		"list":            version + "\n",
+		version + ".info": `{"Version":"` + version + `"}`,
+		version + ".mod":  mod,
+	})
+
+	f, err := os.Create(filepath.Join(base, version+".zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(module + "@" + version + "/go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(mod)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDependenciesDownloadLeavesCheckoutAlone(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go isn't installed")
+	}
+
+	proxy := t.TempDir()
+	writeProxy(t, proxy, "example.com/dep", "v1.0.0", "1.22")
+	t.Setenv("GOPROXY", "file://"+filepath.ToSlash(proxy))
+	cache := t.TempDir()
+	t.Setenv("GOMODCACHE", cache)
+	t.Setenv("GOSUMDB", "off")
+	// the module cache is read-only, which t.TempDir can't remove
+	t.Cleanup(func() { exec.Command("go", "clean", "-modcache").Run() })
+
+	repo := t.TempDir()
+	mod := "module x\n\ngo 1.21\n\nrequire (\n\texample.com/dep v1.0.0\n\texample.com/gone v1.0.0 // indirect\n)\n"
+	writeFiles(t, repo, map[string]string{"go.mod": mod})
+
+	deps, unchecked, err := dependencies(context.Background(), repo, []byte(mod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 1 || deps[0].path != "example.com/dep" || deps[0].goVersion != "1.22" {
+		t.Errorf("dependencies() = %v, want example.com/dep@v1.0.0 (go 1.22)", deps)
+	}
+	if len(unchecked) != 1 || unchecked[0].path != "example.com/gone" {
+		t.Errorf("dependencies() unchecked = %v, want example.com/gone", unchecked)
+	}
+
+	files, err := ioutil.ReadDir(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("download left %d files in the checkout, want only go.mod", len(files))
+	}
+	if read, _ := ioutil.ReadFile(filepath.Join(repo, goMod)); string(read) != mod {
+		t.Errorf("download changed go.mod to\n%s", read)
+	}
+}
+
+func TestRequires(t *testing.T) {
+	mod := []byte(`module x
+
+go 1.21
+
+require example.com/single v1.0.0
+
+require (
+	example.com/a v1.2.3
+	example.com/b v0.1.0 // indirect
+
+	// a comment line
+	example.com/c v2.0.0+incompatible
+)
+
+require example.com/bad
+`)
+
+	want := []requirement{
+		{path: "example.com/single", version: "v1.0.0"},
+		{path: "example.com/a", version: "v1.2.3"},
+		{path: "example.com/b", version: "v0.1.0", indirect: true},
+		{path: "example.com/c", version: "v2.0.0+incompatible"},
+	}
+	if got := requires(mod); !reflect.DeepEqual(got, want) {
+		t.Errorf("requires() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	reps := replaces([]byte(`module x
+
+replace example.com/a => ../a
+
+replace (
+	example.com/b v1.0.0 => example.com/fork/b v1.0.1
+	example.com/b => example.com/fork/b v1.9.0
+	example.com/c v1.0.0 => ./c
+	example.com/d => example.com/fork/d v1.0.0
+	example.com/d => example.com/fork2/d v2.0.0
+	example.com/broken =>
+)
+`))
+	if len(reps) != 6 {
+		t.Fatalf("replaces() = %+v, want 6 directives", reps)
+	}
+
+	tests := []struct {
+		req      requirement
+		want     replacement
+		replaced bool
+	}{
+		{
+			req:      requirement{path: "example.com/a", version: "v1.0.0"},
+			want:     replacement{path: "example.com/a", newPath: "../a"},
+			replaced: true,
+		},
+		{
+			req:      requirement{path: "example.com/b", version: "v1.0.0"},
+			want:     replacement{path: "example.com/b", newPath: "example.com/fork/b", newVersion: "v1.9.0"},
+			replaced: true,
+		},
+		{
+			req:      requirement{path: "example.com/c", version: "v1.0.0"},
+			want:     replacement{path: "example.com/c", version: "v1.0.0", newPath: "./c"},
+			replaced: true,
+		},
+		{
+			req: requirement{path: "example.com/c", version: "v1.1.0"},
+		},
+		{
+			req:      requirement{path: "example.com/d", version: "v0.1.0"},
+			want:     replacement{path: "example.com/d", newPath: "example.com/fork2/d", newVersion: "v2.0.0"},
+			replaced: true,
+		},
+		{
+			req: requirement{path: "example.com/e", version: "v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.req.path+"@"+tt.req.version, func(t *testing.T) {
+			got, ok := replace(tt.req, reps)
+			if ok != tt.replaced || got != tt.want {
+				t.Errorf("replace() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.replaced)
+			}
+		})
+	}
+}
+
+func TestReconcileDirectives(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		before  string
+		after   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "unchanged",
+			target: "1.22",
+			before: "module x\n\ngo 1.22\n",
+			after:  "module x\n\ngo 1.22\n",
+			want:   "module x\n\ngo 1.22\n",
+		},
+		{
+			name:   "go directive spelled with patch",
+			target: "1.22",
+			before: "module x\n\ngo 1.22\n",
+			after:  "module x\n\ngo 1.22.0\n",
+			want:   "module x\n\ngo 1.22.0\n",
+		},
+		{
+			name:   "go directive lowered",
+			target: "1.22.3",
+			before: "module x\n\ngo 1.22.3\n",
+			after:  "module x\n\ngo 1.22.1\n",
+			want:   "module x\n\ngo 1.22.3\n",
+		},
+		{
+			name:   "toolchain added",
+			target: "1.22",
+			before: "module x\n\ngo 1.22\n",
+			after:  "module x\n\ngo 1.22\n\ntoolchain go1.23.2\n",
+			want:   "module x\n\ngo 1.22\n",
+		},
+		{
+			name:   "toolchain changed",
+			target: "1.22",
+			before: "module x\n\ngo 1.22\n\ntoolchain go1.22.5\n",
+			after:  "module x\n\ngo 1.22\n\ntoolchain go1.23.2\n",
+			want:   "module x\n\ngo 1.22\n\ntoolchain go1.22.5\n",
+		},
+		{
+			name:    "dependency needs a newer go",
+			target:  "1.22",
+			before:  "module x\n\ngo 1.22\n",
+			after:   "module x\n\ngo 1.23\n",
+			want:    "module x\n\ngo 1.23\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{goMod: tt.after})
+
+			w := NewWorker(dir, Config{Version: tt.target})
+			err := w.reconcileDirectives(dir, []byte(tt.before), []byte(tt.after))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reconcileDirectives() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if read, _ := ioutil.ReadFile(filepath.Join(dir, goMod)); string(read) != tt.want {
+				t.Errorf("go.mod =\n%s\nwant\n%s", read, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffRequires(t *testing.T) {
+	before := []requirement{{path: "a", version: "v1.0.0"}, {path: "b", version: "v1.0.0"}, {path: "c", version: "v1.0.0"}}
+	after := []requirement{{path: "a", version: "v1.0.0"}, {path: "b", version: "v1.1.0"}, {path: "d", version: "v0.1.0"}}
+
+	want := []DepChange{
+		{Path: "b", From: "v1.0.0", To: "v1.1.0"},
+		{Path: "d", To: "v0.1.0"},
+		{Path: "c", From: "v1.0.0"},
+	}
+	if got := diffRequires(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffRequires() = %+v, want %+v", got, want)
+	}
+}
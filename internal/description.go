@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// verification is the outcome of one verify command.
+type verification struct {
+	command string
+	err     error
+	took    time.Duration
+}
+
+// changedFiles lists the files changed in the checkout, edited, added or
+// removed, relative to its root.
+func changedFiles(ctx context.Context, dir string) ([]string, error) {
+	out, err := git(ctx, dir, "ls-files", "--modified", "--deleted", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var files []string
+	for _, f := range strings.Split(out, "\n") {
+		if f != "" && !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// describe renders the pull request description: the version change, every
+// changed file, the verification, the dependency updates and the release
+// notes of every minor version crossed. changed are the files of the
+// commit when it was made in this run, the planned edits are listed
+// otherwise.
+func (w *Worker) describe(rp RepoPlan, changed []string, verified bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Bumps go from **%s** to **%s**.\n", rp.From, rp.To)
+
+	b.WriteString("\n### Changed files\n\n")
+	planned := map[string]bool{}
+	for _, e := range rp.Edits {
+		planned[e.Path] = true
+		summary := e.Summary
+		if summary == "" {
+			summary = "edited"
+		}
+		fmt.Fprintf(&b, "- `%s`: %s\n", e.Path, summary)
+	}
+
+	vendored := 0
+	for _, f := range changed {
+		switch {
+		case planned[f]:
+		case strings.HasPrefix(f, "vendor/") || strings.Contains(f, "/vendor/"):
+			vendored++
+		case path.Base(f) == "go.sum":
+			fmt.Fprintf(&b, "- `%s`: dependency checksums\n", f)
+		case path.Base(f) == goMod:
+			fmt.Fprintf(&b, "- `%s`: dependency requirements\n", f)
+		default:
+			fmt.Fprintf(&b, "- `%s`: changed by a hook or the dependency update\n", f)
+		}
+	}
+	if vendored > 0 {
+		fmt.Fprintf(&b, "- %d vendored files\n", vendored)
+	}
+
+	b.WriteString("\n### Verification\n\n")
+	switch {
+	case !verified:
+		b.WriteString("Verified in an earlier run.\n")
+	case len(w.verification) == 0:
+		b.WriteString("No verification configured.\n")
+	default:
+		for _, v := range w.verification {
+			if v.err != nil {
+				fmt.Fprintf(&b, "- ❌ `%s` (%s): %v\n", v.command, roundDuration(v.took), v.err)
+			} else {
+				fmt.Fprintf(&b, "- ✅ `%s` (%s)\n", v.command, roundDuration(v.took))
+			}
+		}
+	}
+
+	if table := depsTable(w.depChanges); table != "" {
+		b.WriteString("\n### Dependencies\n\n" + table)
+	}
+
+	if notes := releaseNotesBetween(rp.From, rp.To); len(notes) > 0 {
+		b.WriteString("\n### Go release notes\n")
+		for _, n := range notes {
+			fmt.Fprintf(&b, "\n**[Go %s](%s)**\n", n.version, n.url())
+			if len(n.notes) > 0 {
+				b.WriteString("\n")
+			}
+			for _, note := range n.notes {
+				fmt.Fprintf(&b, "- %s\n", note)
+			}
+		}
+	}
+
+	return b.String()
+}
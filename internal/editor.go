@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+)
+
+// Editor rewrites one kind of file for the bump. Files are given relative
+// to the repository root, slash separated; Edit returns the content
+// unchanged when there is nothing to do. An editor with a
+// Summary(from, to string) string method describes its change in a line of
+// the pull request.
+type Editor interface {
+	Match(file string) bool
+	Edit(file string, content []byte, from, to string) ([]byte, error)
+}
+
+// summarizer is the optional Summary of an editor, editors without one are
+// summarized as "edited".
+type summarizer interface {
+	Summary(from, to string) string
+}
+
+// goModEditor sets the go directive of every go.mod.
+type goModEditor struct{}
+
+func (goModEditor) Match(file string) bool {
+	return path.Base(file) == goMod
+}
+
+func (goModEditor) Edit(file string, content []byte, from, to string) ([]byte, error) {
+	return setGoDirective(content, to), nil
+}
+
+func (goModEditor) Summary(from, to string) string {
+	return fmt.Sprintf("go directive %s → %s", from, to)
+}
+
+// versionEditor replaces the old version in files matching the configured
+// editors by name, or the repository's extra files by path.
+type versionEditor struct {
+	names []string
+	paths []string
+}
+
+func (e versionEditor) Match(file string) bool {
+	for _, pattern := range e.names {
+		if matched, _ := path.Match(pattern, path.Base(file)); matched {
+			return true
+		}
+	}
+
+	for _, pattern := range e.paths {
+		if matched, _ := path.Match(pattern, file); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e versionEditor) Edit(file string, content []byte, from, to string) ([]byte, error) {
+	if from == "" {
+		return content, nil
+	}
+
+	return bytes.Replace(content, []byte(from), []byte(to), -1), nil
+}
+
+func (versionEditor) Summary(from, to string) string {
+	return fmt.Sprintf("go version %s → %s", from, to)
+}
+
+// editors are the built-in editors of a repository followed by the ones
+// added with UseEditors.
+func (w *Worker) editors() []Editor {
+	editors := []Editor{
+		goModEditor{},
+		versionEditor{names: w.cfg.Editors, paths: w.repoCfg.ExtraFiles},
+	}
+
+	if w.cfg.Modernize {
+		editors = append(editors, modernizeEditor{})
+	}
+
+	return append(editors, w.extraEditors...)
+}
@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gammazero/workerpool"
+)
+
+// Support statuses of a go version. Go supports the two newest minors, a
+// minor loses support when the second minor after it is released.
+const (
+	EOLSupported = "supported"
+	EOLNearing   = "nearing-eol"
+	EOLEnded     = "eol"
+
+	// eolWarning is how long before its end of life a version is reported
+	// as nearing it.
+	eolWarning = 90 * 24 * time.Hour
+	// releaseCadence is the months between two minor releases, used to
+	// estimate the dates of releases missing from goReleases.
+	releaseCadence = 6
+	dateLayout     = "2006-01-02"
+)
+
+// goReleases are the release dates of the go minors. They date the ends of
+// life and stand in for the release index when it can't be read.
+var goReleases = map[string]string{
+	"1.5":  "2015-08-19",
+	"1.6":  "2016-02-17",
+	"1.7":  "2016-08-15",
+	"1.8":  "2017-02-16",
+	"1.9":  "2017-08-24",
+	"1.10": "2018-02-16",
+	"1.11": "2018-08-24",
+	"1.12": "2019-02-25",
+	"1.13": "2019-09-03",
+	"1.14": "2020-02-25",
+	"1.15": "2020-08-11",
+	"1.16": "2021-02-16",
+	"1.17": "2021-08-16",
+	"1.18": "2022-03-15",
+	"1.19": "2022-08-02",
+	"1.20": "2023-02-01",
+	"1.21": "2023-08-08",
+	"1.22": "2024-02-06",
+	"1.23": "2024-08-13",
+	"1.24": "2025-02-11",
+	"1.25": "2025-08-12",
+	"1.26": "2026-02-10",
+	"1.27": "2026-08-11",
+}
+
+// KnownMinors are the minors of the built-in release table, newest first.
+func KnownMinors() []string {
+	minors := make([]string, 0, len(goReleases))
+	for minor := range goReleases {
+		minors = append(minors, minor)
+	}
+
+	sort.Slice(minors, func(i, j int) bool {
+		return compareVersions(minors[i], minors[j]) > 0
+	})
+
+	return minors
+}
+
+// Minors are the minors with a stable release in the index, newest first.
+func (r ReleaseIndex) Minors() ([]string, error) {
+	releases, err := r.Releases()
+	if err != nil {
+		return nil, err
+	}
+
+	minors := stableMinors(releases)
+	if len(minors) == 0 {
+		return nil, fmt.Errorf("release index %s has no stable release", r.url)
+	}
+
+	return minors, nil
+}
+
+// releaseDate is the release date of a minor, estimated from the newest
+// dated minor for those missing from goReleases.
+func releaseDate(minor string) (date time.Time, estimated bool) {
+	if released, ok := goReleases[minor]; ok {
+		date, _ := time.Parse(dateLayout, released)
+		return date, false
+	}
+
+	newest := KnownMinors()[0]
+	date, _ = time.Parse(dateLayout, goReleases[newest])
+	steps := versionParts(minor)[1] - versionParts(newest)[1]
+
+	return date.AddDate(0, steps*releaseCadence, 0), true
+}
+
+// EOLStatus is the support status of the go version of a repository:
+// the toolchain directive of its go.mod or else the go directive. EOL is
+// the day its support ended or is expected to end, Teams the code owners
+// of go.mod.
+type EOLStatus struct {
+	Repo      string   `json:"repo"`
+	Module    string   `json:"module,omitempty"`
+	Go        string   `json:"go,omitempty"`
+	Toolchain string   `json:"toolchain,omitempty"`
+	Status    string   `json:"status,omitempty"`
+	EOL       string   `json:"eol,omitempty"`
+	Estimated bool     `json:"estimated,omitempty"`
+	Teams     []string `json:"teams,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// classify sets the status of the version against the supported minors,
+// newest first, at now.
+func (s *EOLStatus) classify(minors []string, now time.Time) {
+	version := s.Toolchain
+	if version == "" {
+		version = s.Go
+	}
+
+	parts := versionParts(version)
+	if len(parts) < 2 {
+		s.Error = fmt.Sprintf("unknown go version %q", version)
+		return
+	}
+
+	eol, estimated := releaseDate(fmt.Sprintf("%d.%d", parts[0], parts[1]+2))
+	s.EOL, s.Estimated = eol.Format(dateLayout), estimated
+
+	oldest := minors[0]
+	if len(minors) > 1 {
+		oldest = minors[1]
+	}
+
+	switch {
+	case compareVersions(minorOf(version), oldest) < 0 || !now.Before(eol):
+		s.Status = EOLEnded
+	case eol.Sub(now) < eolWarning:
+		s.Status = EOLNearing
+	default:
+		s.Status = EOLSupported
+	}
+}
+
+// EOL reports the support status of the go version of every repository
+// with a go.mod, minors are the released minors, newest first.
+func (w Worker) EOL(ctx context.Context, minors []string, now time.Time) ([]EOLStatus, error) {
+	if len(minors) == 0 {
+		return nil, fmt.Errorf("no go releases to check against")
+	}
+
+	repos, err := w.repos()
+	if err != nil {
+		return nil, err
+	}
+
+	// local checkouts are only read, a worktree would gain nothing
+	w.cfg.Worktree = false
+
+	var mu sync.Mutex
+	var statuses []EOLStatus
+	var wg sync.WaitGroup
+	wp := workerpool.New(w.cfg.Concurrency)
+	for _, name := range repos {
+		name := name
+		wg.Add(1)
+		wp.Submit(func() {
+			defer wg.Done()
+			status, ok := w.eolRepo(ctx, name, minors, now)
+			if !ok {
+				return
+			}
+
+			mu.Lock()
+			statuses = append(statuses, status)
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+	wp.Stop()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Repo < statuses[j].Repo })
+	return statuses, ctx.Err()
+}
+
+// eolRepo reads the go version and code owners of a repository, false for
+// a repository without go.mod.
+func (w Worker) eolRepo(ctx context.Context, name string, minors []string, now time.Time) (EOLStatus, bool) {
+	status := EOLStatus{Repo: name}
+	w.log = w.log.With("repo", name)
+
+	rp := RepoPlan{Name: name}
+	if url, ok := w.remotes[name]; ok {
+		rp.Remote = url
+	} else {
+		rp.Dir = filepath.Join(w.path, name)
+	}
+
+	if err := ctx.Err(); err != nil {
+		status.Error = Redact(err.Error())
+		return status, true
+	}
+
+	dir, cleanup, err := w.checkout(ctx, rp)
+	if err != nil {
+		w.log.Error("checkout failed", "err", err)
+		status.Error = Redact(err.Error())
+		return status, true
+	}
+	defer cleanup()
+
+	read, err := ioutil.ReadFile(filepath.Join(dir, goMod))
+	if os.IsNotExist(err) {
+		w.log.Debug("no go.mod")
+		return status, false
+	}
+	if err != nil {
+		status.Error = err.Error()
+		return status, true
+	}
+
+	status.Module, status.Go, status.Toolchain = moduleDirective(read), goDirective(read), toolchainDirective(read)
+	status.classify(minors, now)
+
+	rules, err := codeOwners(dir)
+	if err != nil {
+		w.log.Warn("reading CODEOWNERS failed", "err", err)
+	}
+	users, teams := owners(rules, []string{goMod})
+	if len(teams) == 0 {
+		teams = users
+	}
+	status.Teams = teams
+
+	w.log.Debug("classified", "go", status.Go, "toolchain", status.Toolchain, "status", status.Status)
+	return status, true
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEOLStatusClassify(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse(dateLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	minors := []string{"1.27", "1.26", "1.25"}
+
+	tests := []struct {
+		name      string
+		status    EOLStatus
+		minors    []string
+		now       string
+		want      string
+		eol       string
+		estimated bool
+		err       bool
+	}{
+		{
+			name:      "newest minor",
+			status:    EOLStatus{Go: "1.27.0"},
+			now:       "2026-09-01",
+			want:      EOLSupported,
+			eol:       "2027-08-11",
+			estimated: true,
+		},
+		{
+			name:      "second newest minor",
+			status:    EOLStatus{Go: "1.26"},
+			now:       "2026-09-01",
+			want:      EOLSupported,
+			eol:       "2027-02-11",
+			estimated: true,
+		},
+		{
+			name:      "nearing its end of life",
+			status:    EOLStatus{Go: "1.26.3"},
+			now:       "2027-01-01",
+			want:      EOLNearing,
+			eol:       "2027-02-11",
+			estimated: true,
+		},
+		{
+			name:   "past its end of life",
+			status: EOLStatus{Go: "1.25.4"},
+			now:    "2026-09-01",
+			want:   EOLEnded,
+			eol:    "2026-08-11",
+		},
+		{
+			name:   "on the day of its end of life",
+			status: EOLStatus{Go: "1.25"},
+			now:    "2026-08-11",
+			want:   EOLEnded,
+			eol:    "2026-08-11",
+		},
+		{
+			name:   "dated end of life nearing",
+			status: EOLStatus{Go: "1.25"},
+			minors: []string{"1.26", "1.25"},
+			now:    "2026-07-01",
+			want:   EOLNearing,
+			eol:    "2026-08-11",
+		},
+		{
+			name:   "index ahead of the release table",
+			status: EOLStatus{Go: "1.25"},
+			minors: []string{"1.27", "1.26"},
+			now:    "2026-07-01",
+			want:   EOLEnded,
+			eol:    "2026-08-11",
+		},
+		{
+			name:      "toolchain over go directive",
+			status:    EOLStatus{Go: "1.21", Toolchain: "go1.27.1"},
+			now:       "2026-09-01",
+			want:      EOLSupported,
+			eol:       "2027-08-11",
+			estimated: true,
+		},
+		{
+			name:      "only one minor released",
+			status:    EOLStatus{Go: "1.27"},
+			minors:    []string{"1.25"},
+			now:       "2026-09-01",
+			want:      EOLSupported,
+			eol:       "2027-08-11",
+			estimated: true,
+		},
+		{
+			name:   "no go version",
+			status: EOLStatus{},
+			now:    "2026-09-01",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.minors == nil {
+				tt.minors = minors
+			}
+			s := tt.status
+			s.classify(tt.minors, date(tt.now))
+			if (s.Error != "") != tt.err {
+				t.Fatalf("classify() error = %q, want error %v", s.Error, tt.err)
+			}
+			if s.Status != tt.want || s.EOL != tt.eol || s.Estimated != tt.estimated {
+				t.Errorf("classify() = %s, eol %s, estimated %v, want %s, eol %s, estimated %v",
+					s.Status, s.EOL, s.Estimated, tt.want, tt.eol, tt.estimated)
+			}
+		})
+	}
+}
+
+func TestReleaseDate(t *testing.T) {
+	tests := []struct {
+		minor     string
+		want      string
+		estimated bool
+	}{
+		{minor: "1.21", want: "2023-08-08"},
+		{minor: "1.25", want: "2025-08-12"},
+		{minor: "1.27", want: "2026-08-11"},
+		{minor: "1.28", want: "2027-02-11", estimated: true},
+		{minor: "1.29", want: "2027-08-11", estimated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.minor, func(t *testing.T) {
+			date, estimated := releaseDate(tt.minor)
+			if got := date.Format(dateLayout); got != tt.want || estimated != tt.estimated {
+				t.Errorf("releaseDate(%q) = %s, %v, want %s, %v", tt.minor, got, estimated, tt.want, tt.estimated)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// forkRemote is the remote the fork is added as in the checkout.
+	forkRemote = "gobump-fork"
+
+	forkPoll    = 2 * time.Second
+	forkTimeout = 5 * time.Minute
+)
+
+// Fork is the fork of a repository the bump branch is pushed to in fork
+// mode. Path is `owner/name`, CloneURL uses the scheme of origin.
+type Fork struct {
+	Path     string
+	CloneURL string
+}
+
+// fork creates or reuses the fork of the repository under the configured
+// owner and adds it as a remote of the checkout, fetching the bump branch
+// so a leftover of an earlier run can be replaced. The remote is removed
+// again with removeForkRemote.
+func (w *Worker) fork(ctx context.Context, dir, branch string) (Fork, error) {
+	forker, ok := w.provider.(Forker)
+	if !ok {
+		return Fork{}, fmt.Errorf("fork mode: the provider can't create forks")
+	}
+
+	fork, err := forker.Fork(ctx, dir, w.cfg.Fork)
+	if err != nil {
+		return Fork{}, err
+	}
+
+	if url, err := git(ctx, dir, "remote", "get-url", forkRemote); err != nil {
+		if _, err := git(ctx, dir, "remote", "add", forkRemote, fork.CloneURL); err != nil {
+			return Fork{}, err
+		}
+	} else if url != fork.CloneURL {
+		if _, err := git(ctx, dir, "remote", "set-url", forkRemote, fork.CloneURL); err != nil {
+			return Fork{}, err
+		}
+	}
+
+	// a missing branch is fine, the fetch only makes an existing one known
+	err = retry(ctx, w.cfg.Retry, "fetch", func() error {
+		_, err := git(ctx, dir, "fetch", forkRemote, branch)
+		return err
+	})
+	if err != nil && !strings.Contains(err.Error(), "couldn't find remote ref") {
+		w.removeForkRemote(ctx, dir)
+		return Fork{}, err
+	}
+
+	w.log.Info("using fork", "fork", fork.Path)
+	return fork, nil
+}
+
+// removeForkRemote drops the remote fork added, the checkout may be the
+// user's own repository.
+func (w *Worker) removeForkRemote(ctx context.Context, dir string) {
+	if _, err := git(ctx, dir, "remote", "remove", forkRemote); err != nil {
+		w.log.Warn("removing the fork remote failed", "remote", forkRemote, "err", err)
+	}
+}
+
+// forkPath is where the fork of upstream lands under owner, forks keep the
+// name of the repository.
+func forkPath(owner, upstream string) string {
+	return owner + "/" + upstream[strings.LastIndex(upstream, "/")+1:]
+}
+
+// cloneURL picks the URL of the fork with the scheme of origin.
+func cloneURL(origin, httpURL, sshURL string) string {
+	if strings.HasPrefix(origin, "https://") || strings.HasPrefix(origin, "http://") {
+		return httpURL
+	}
+
+	return sshURL
+}
+
+// waitForFork polls until the freshly created fork is ready, forks are
+// created asynchronously.
+func waitForFork(ctx context.Context, fork string, ready func() (bool, error)) error {
+	deadline := time.Now().Add(forkTimeout)
+	for {
+		ok, err := ready()
+		if err != nil || ok {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("fork %s not ready after %v", fork, forkTimeout)
+		}
+
+		if err := sleep(ctx, forkPoll); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type github struct {
+	apiClient
+
+	mu    sync.Mutex
+	users map[string]string
+}
+
+func newGitHub(api apiTransport, cfg Retry) *github {
+	return &github{apiClient: newAPIClient(api, cfg), users: map[string]string{}}
+}
+
+// OpenPullRequest opens the pull request against the default branch of the
+// repository origin points to.
+func (g *github) OpenPullRequest(ctx context.Context, dir string, pr NewPullRequest) (string, error) {
+	origin, err := git(ctx, dir, "remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	host, repo := remoteHost(origin), "repos/"+filepath.ToSlash(remoteName(origin))
+
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := g.call(ctx, host, http.MethodGet, repo, nil, &info); err != nil {
+		return "", err
+	}
+
+	// a branch of another repository is named by its owner, the head of
+	// an existing pull request is always looked up with it
+	owner := strings.SplitN(filepath.ToSlash(remoteName(origin)), "/", 2)[0]
+	head := pr.Head
+	if pr.Fork != "" {
+		owner = strings.SplitN(pr.Fork, "/", 2)[0]
+		head = owner + ":" + head
+	}
+
+	in := map[string]interface{}{
+		"title": pr.Title,
+		"body":  pr.Body,
+		"head":  head,
+		"base":  info.DefaultBranch,
+		"draft": pr.Draft,
+	}
+	var created githubPull
+	if err := g.call(ctx, host, http.MethodPost, repo+"/pulls", in, &created); err != nil {
+		// a run which crashed after opening it, or a repeated one, finds
+		// the pull request of the branch already open
+		if !hasStatus(err, http.StatusUnprocessableEntity) || !strings.Contains(err.Error(), "already exists") {
+			return "", err
+		}
+		if created, err = g.openPull(ctx, host, repo, owner+":"+pr.Head); err != nil {
+			return "", err
+		}
+		loggerFrom(ctx).Info("pull request already open", "url", created.HTMLURL)
+	}
+
+	// the pull request exists from here on, every option is still tried
+	// when another one fails
+	var errs []error
+	issue := fmt.Sprintf("%s/issues/%d", repo, created.Number)
+	if len(pr.Labels) > 0 {
+		labels := map[string][]string{"labels": pr.Labels}
+		if err := g.call(ctx, host, http.MethodPost, issue+"/labels", labels, nil); err != nil {
+			errs = append(errs, fmt.Errorf("labels: %v", err))
+		}
+	}
+
+	if len(pr.Reviewers) > 0 {
+		if login, err := g.login(ctx, host); err != nil {
+			errs = append(errs, fmt.Errorf("reviewers: %v", err))
+		} else {
+			pr.Reviewers = without(pr.Reviewers, login)
+		}
+	}
+	if len(pr.Reviewers) > 0 || len(pr.TeamReviewers) > 0 {
+		reviewers := map[string][]string{}
+		if len(pr.Reviewers) > 0 {
+			reviewers["reviewers"] = pr.Reviewers
+		}
+		if len(pr.TeamReviewers) > 0 {
+			reviewers["team_reviewers"] = pr.TeamReviewers
+		}
+		path := fmt.Sprintf("%s/pulls/%d/requested_reviewers", repo, created.Number)
+		if err := g.call(ctx, host, http.MethodPost, path, reviewers, nil); err != nil {
+			errs = append(errs, fmt.Errorf("reviewers: %v", err))
+		}
+	}
+
+	update := map[string]interface{}{}
+	if len(pr.Assignees) > 0 {
+		update["assignees"] = pr.Assignees
+	}
+	if pr.Milestone != "" {
+		if number, err := g.milestone(ctx, host, repo, pr.Milestone); err != nil {
+			errs = append(errs, fmt.Errorf("milestone: %v", err))
+		} else {
+			update["milestone"] = number
+		}
+	}
+	if len(update) > 0 {
+		if err := g.call(ctx, host, http.MethodPatch, issue, update, nil); err != nil {
+			errs = append(errs, fmt.Errorf("assignees and milestone: %v", err))
+		}
+	}
+
+	if pr.AutoMerge != "" {
+		if err := g.enableAutoMerge(ctx, host, created.NodeID, pr.AutoMerge); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return created.HTMLURL, errors.Join(errs...)
+}
+
+type githubPull struct {
+	Number  int    `json:"number"`
+	NodeID  string `json:"node_id"`
+	HTMLURL string `json:"html_url"`
+}
+
+// openPull returns the open pull request of the head, `owner:branch`.
+func (g *github) openPull(ctx context.Context, host, repo, head string) (githubPull, error) {
+	var pulls []githubPull
+	path := repo + "/pulls?state=open&head=" + url.QueryEscape(head)
+	if err := g.call(ctx, host, http.MethodGet, path, nil, &pulls); err != nil {
+		return githubPull{}, err
+	}
+
+	if len(pulls) == 0 {
+		return githubPull{}, fmt.Errorf("%s: no open pull request for %s", repo, head)
+	}
+
+	return pulls[0], nil
+}
+
+// enableAutoMerge has GitHub merge the pull request once its requirements
+// are met, which is only available through GraphQL.
+func (g *github) enableAutoMerge(ctx context.Context, host, id, method string) error {
+	query := map[string]interface{}{
+		"query": `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId }
+}`,
+		"variables": map[string]string{"id": id, "method": strings.ToUpper(method)},
+	}
+
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := g.call(ctx, host, http.MethodPost, "graphql", query, &result); err != nil {
+		return err
+	}
+
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("enabling auto-merge: %s", result.Errors[0].Message)
+	}
+
+	return nil
+}
+
+// Fork returns the fork of the repository origin points to under owner, a
+// user or organization, creating it if there is none yet.
+func (g *github) Fork(ctx context.Context, dir, owner string) (Fork, error) {
+	origin, err := git(ctx, dir, "remote", "get-url", "origin")
+	if err != nil {
+		return Fork{}, err
+	}
+	host, upstream := remoteHost(origin), filepath.ToSlash(remoteName(origin))
+
+	var repo struct {
+		FullName string `json:"full_name"`
+		Fork     bool   `json:"fork"`
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		Parent   *struct {
+			FullName string `json:"full_name"`
+		} `json:"parent"`
+	}
+	err = g.call(ctx, host, http.MethodGet, "repos/"+forkPath(owner, upstream), nil, &repo)
+	if isNotFound(err) {
+		login, err := g.login(ctx, host)
+		if err != nil {
+			return Fork{}, err
+		}
+		in := map[string]interface{}{"default_branch_only": true}
+		if !strings.EqualFold(owner, login) {
+			in["organization"] = owner
+		}
+
+		// GitHub answers with the fork before its git repository exists
+		if err := g.call(ctx, host, http.MethodPost, "repos/"+upstream+"/forks", in, &repo); err != nil {
+			return Fork{}, err
+		}
+		url := cloneURL(origin, repo.CloneURL, repo.SSHURL)
+		err = waitForFork(ctx, repo.FullName, func() (bool, error) {
+			_, err := git(ctx, dir, "ls-remote", "--heads", url)
+			return err == nil, nil
+		})
+		if err != nil {
+			return Fork{}, err
+		}
+	} else if err != nil {
+		return Fork{}, err
+	}
+
+	if !repo.Fork || repo.Parent == nil || !strings.EqualFold(repo.Parent.FullName, upstream) {
+		return Fork{}, fmt.Errorf("%s is not a fork of %s", repo.FullName, upstream)
+	}
+
+	return Fork{Path: repo.FullName, CloneURL: cloneURL(origin, repo.CloneURL, repo.SSHURL)}, nil
+}
+
+// Merge merges the pull request now.
+func (g *github) Merge(ctx context.Context, url, method string) error {
+	ref, err := parsePRURL(url)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("repos/%s/%s/pulls/%d/merge", ref.owner, ref.repo, ref.number)
+	return g.call(ctx, ref.host, http.MethodPut, path, map[string]string{"merge_method": method}, nil)
+}
+
+// milestone returns the number of the open milestone with the title, a
+// number is taken as is.
+func (g *github) milestone(ctx context.Context, host, repo, title string) (int, error) {
+	if number, err := strconv.Atoi(title); err == nil {
+		return number, nil
+	}
+
+	var milestones []struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+	}
+	if err := g.call(ctx, host, http.MethodGet, repo+"/milestones?state=open&per_page=100", nil, &milestones); err != nil {
+		return 0, err
+	}
+
+	for _, m := range milestones {
+		if m.Title == title {
+			return m.Number, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: no open milestone %q", repo, title)
+}
+
+func without(list []string, drop string) []string {
+	var result []string
+	for _, s := range list {
+		if !strings.EqualFold(s, drop) {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+// login is the user the pull requests are opened as, who can't review them.
+func (g *github) login(ctx context.Context, host string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if login, ok := g.users[host]; ok {
+		return login, nil
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := g.call(ctx, host, http.MethodGet, "user", nil, &user); err != nil {
+		return "", err
+	}
+	g.users[host] = user.Login
+
+	return user.Login, nil
+}
+
+func (g *github) PullRequest(ctx context.Context, url string) (PullRequest, error) {
+	ref, err := parsePRURL(url)
+	if err != nil {
+		return PullRequest{}, err
+	}
+
+	host, repo := ref.host, fmt.Sprintf("repos/%s/%s", ref.owner, ref.repo)
+	var pr struct {
+		State          string `json:"state"`
+		Merged         bool   `json:"merged"`
+		MergeableState string `json:"mergeable_state"`
+		Head           struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := g.call(ctx, host, http.MethodGet, fmt.Sprintf("%s/pulls/%d", repo, ref.number), nil, &pr); err != nil {
+		return PullRequest{}, err
+	}
+
+	result := PullRequest{
+		Repo:      ref.owner + "/" + ref.repo,
+		URL:       url,
+		State:     pr.State,
+		Conflicts: pr.MergeableState == "dirty",
+	}
+	if pr.Merged {
+		result.State = prMerged
+	}
+
+	if result.Checks, err = g.checks(ctx, host, repo, pr.Head.SHA); err != nil {
+		return result, err
+	}
+
+	if result.Review, err = g.review(ctx, host, repo, ref.number); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// checks combines the check runs and the legacy commit statuses of a commit.
+func (g *github) checks(ctx context.Context, host, repo, sha string) (string, error) {
+	type checkRun struct {
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+	}
+	var checkRuns []checkRun
+	for page := 1; ; page++ {
+		var runs struct {
+			TotalCount int        `json:"total_count"`
+			CheckRuns  []checkRun `json:"check_runs"`
+		}
+		path := fmt.Sprintf("%s/commits/%s/check-runs?per_page=100&page=%d", repo, sha, page)
+		if err := g.call(ctx, host, http.MethodGet, path, nil, &runs); err != nil {
+			return "", err
+		}
+
+		checkRuns = append(checkRuns, runs.CheckRuns...)
+		if len(runs.CheckRuns) < 100 || len(checkRuns) >= runs.TotalCount {
+			break
+		}
+	}
+
+	// the combined state covers every status, only the count is needed
+	var status struct {
+		State      string `json:"state"`
+		TotalCount int    `json:"total_count"`
+	}
+	if err := g.call(ctx, host, http.MethodGet, fmt.Sprintf("%s/commits/%s/status", repo, sha), nil, &status); err != nil {
+		return "", err
+	}
+
+	var states []string
+	for _, run := range checkRuns {
+		switch {
+		case run.Status != "completed":
+			states = append(states, checksPending)
+		case run.Conclusion == "success" || run.Conclusion == "neutral" || run.Conclusion == "skipped":
+			states = append(states, checksSuccess)
+		default:
+			states = append(states, checksFailure)
+		}
+	}
+	if status.TotalCount > 0 {
+		switch status.State {
+		case "success":
+			states = append(states, checksSuccess)
+		case "pending":
+			states = append(states, checksPending)
+		default:
+			states = append(states, checksFailure)
+		}
+	}
+
+	return combineChecks(states), nil
+}
+
+func combineChecks(states []string) string {
+	if len(states) == 0 {
+		return checksNone
+	}
+
+	result := checksSuccess
+	for _, state := range states {
+		if state == checksFailure {
+			return checksFailure
+		}
+		if state == checksPending {
+			result = checksPending
+		}
+	}
+
+	return result
+}
+
+// review reduces the reviews to the latest one of every reviewer.
+func (g *github) review(ctx context.Context, host, repo string, number int) (string, error) {
+	type pullReview struct {
+		State string `json:"state"`
+		User  struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	var reviews []pullReview
+	for page := 1; ; page++ {
+		var batch []pullReview
+		path := fmt.Sprintf("%s/pulls/%d/reviews?per_page=100&page=%d", repo, number, page)
+		if err := g.call(ctx, host, http.MethodGet, path, nil, &batch); err != nil {
+			return "", err
+		}
+
+		reviews = append(reviews, batch...)
+		if len(batch) < 100 {
+			break
+		}
+	}
+
+	latest := map[string]string{}
+	for _, r := range reviews {
+		if r.State == "APPROVED" || r.State == "CHANGES_REQUESTED" || r.State == "DISMISSED" {
+			latest[r.User.Login] = r.State
+		}
+	}
+
+	result := reviewPending
+	for _, state := range latest {
+		if state == "CHANGES_REQUESTED" {
+			return reviewChangesRequested, nil
+		}
+		if state == "APPROVED" {
+			result = reviewApproved
+		}
+	}
+
+	return result, nil
+}
+
+// Repositories lists the clone URLs of the active Go repositories of an
+// organization, `host/org` on GitHub Enterprise.
+func (g *github) Repositories(ctx context.Context, org string) ([]string, error) {
+	host, org := splitOrg("github", org)
+	var urls []string
+	for page := 1; ; page++ {
+		var repos []struct {
+			CloneURL string `json:"clone_url"`
+			Language string `json:"language"`
+			Archived bool   `json:"archived"`
+		}
+		path := fmt.Sprintf("orgs/%s/repos?per_page=100&page=%d", org, page)
+		if err := g.call(ctx, host, http.MethodGet, path, nil, &repos); err != nil {
+			return nil, err
+		}
+
+		for _, repo := range repos {
+			if repo.Language == "Go" && !repo.Archived {
+				urls = append(urls, repo.CloneURL)
+			}
+		}
+
+		if len(repos) < 100 {
+			return urls, nil
+		}
+	}
+}
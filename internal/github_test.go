@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// originRepo initializes a git repository whose origin is url.
+func originRepo(t *testing.T, url string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{{"init", "-q", dir}, {"-C", dir, "remote", "add", "origin", url}} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	return dir
+}
+
+func TestGitHubOpenPullRequestExisting(t *testing.T) {
+	dir := originRepo(t, "https://github.com/acme/a.git")
+	respond := func(status int, body string) *apiResponse {
+		return &apiResponse{status: status, header: http.Header{}, body: []byte(body)}
+	}
+	repo := respond(200, `{"default_branch":"main"}`)
+	exists := respond(422, `{"message":"Validation Failed","errors":[{"message":"A pull request already exists for acme:next-Go."}]}`)
+
+	tests := []struct {
+		name      string
+		pr        NewPullRequest
+		responses []*apiResponse
+		url       string
+		fails     bool
+		requests  []string
+	}{
+		{
+			name:      "opened",
+			pr:        NewPullRequest{Head: "next-Go"},
+			responses: []*apiResponse{repo, respond(201, `{"number":1,"html_url":"https://github.com/acme/a/pull/1"}`)},
+			url:       "https://github.com/acme/a/pull/1",
+			requests:  []string{"GET repos/acme/a", "POST repos/acme/a/pulls"},
+		},
+		{
+			name:      "already open",
+			pr:        NewPullRequest{Head: "next-Go"},
+			responses: []*apiResponse{repo, exists, respond(200, `[{"number":7,"html_url":"https://github.com/acme/a/pull/7"}]`)},
+			url:       "https://github.com/acme/a/pull/7",
+			requests:  []string{"GET repos/acme/a", "POST repos/acme/a/pulls", "GET repos/acme/a/pulls?state=open&head=acme%3Anext-Go"},
+		},
+		{
+			name:      "already open from a fork",
+			pr:        NewPullRequest{Head: "next-Go", Fork: "bot/a"},
+			responses: []*apiResponse{repo, exists, respond(200, `[{"number":8,"html_url":"https://github.com/acme/a/pull/8"}]`)},
+			url:       "https://github.com/acme/a/pull/8",
+			requests:  []string{"GET repos/acme/a", "POST repos/acme/a/pulls", "GET repos/acme/a/pulls?state=open&head=bot%3Anext-Go"},
+		},
+		{
+			name:      "already exists but closed",
+			pr:        NewPullRequest{Head: "next-Go"},
+			responses: []*apiResponse{repo, exists, respond(200, `[]`)},
+			fails:     true,
+			requests:  []string{"GET repos/acme/a", "POST repos/acme/a/pulls", "GET repos/acme/a/pulls?state=open&head=acme%3Anext-Go"},
+		},
+		{
+			name:      "other validation failure",
+			pr:        NewPullRequest{Head: "next-Go"},
+			responses: []*apiResponse{repo, respond(422, `{"message":"Validation Failed","errors":[{"message":"No commits between main and next-Go"}]}`)},
+			fails:     true,
+			requests:  []string{"GET repos/acme/a", "POST repos/acme/a/pulls"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{responses: tt.responses}
+			url, err := newGitHub(api, Retry{Attempts: 1}).OpenPullRequest(context.Background(), dir, tt.pr)
+			if (err != nil) != tt.fails || url != tt.url {
+				t.Errorf("OpenPullRequest() = %q, %v, want %q, failure %v", url, err, tt.url, tt.fails)
+			}
+			if !reflect.DeepEqual(api.requests, tt.requests) {
+				t.Errorf("OpenPullRequest() requests = %q, want %q", api.requests, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitlab opens merge requests on gitlab.com or a self-hosted GitLab. Team
+// reviewers have no GitLab counterpart and are left out.
+type gitlab struct {
+	apiClient
+
+	mu    sync.Mutex
+	users map[string]int
+}
+
+func newGitLab(api apiTransport, cfg Retry) *gitlab {
+	return &gitlab{apiClient: newAPIClient(api, cfg), users: map[string]int{}}
+}
+
+func gitlabProject(path string) string {
+	return "projects/" + url.PathEscape(path)
+}
+
+// OpenPullRequest opens the merge request against the default branch of
+// the project origin points to.
+func (g *gitlab) OpenPullRequest(ctx context.Context, dir string, pr NewPullRequest) (string, error) {
+	origin, err := git(ctx, dir, "remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	host, project := remoteHost(origin), gitlabProject(remotePath(origin))
+
+	var info struct {
+		ID            int    `json:"id"`
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := g.call(ctx, host, http.MethodGet, project, nil, &info); err != nil {
+		return "", err
+	}
+
+	title := pr.Title
+	if pr.Draft {
+		title = "Draft: " + title
+	}
+	in := map[string]interface{}{
+		"title":                title,
+		"description":          pr.Body,
+		"source_branch":        pr.Head,
+		"target_branch":        info.DefaultBranch,
+		"remove_source_branch": true,
+	}
+	if len(pr.Labels) > 0 {
+		in["labels"] = strings.Join(pr.Labels, ",")
+	}
+	// options which can't be looked up are left out rather than keeping
+	// the merge request from being opened, and reported with it
+	var errs []error
+	if len(pr.Reviewers) > 0 {
+		if ids, err := g.userIDs(ctx, host, pr.Reviewers); err != nil {
+			errs = append(errs, fmt.Errorf("reviewers: %v", err))
+		} else {
+			in["reviewer_ids"] = ids
+		}
+	}
+	if len(pr.Assignees) > 0 {
+		if ids, err := g.userIDs(ctx, host, pr.Assignees); err != nil {
+			errs = append(errs, fmt.Errorf("assignees: %v", err))
+		} else {
+			in["assignee_ids"] = ids
+		}
+	}
+	if pr.Milestone != "" {
+		if id, err := g.milestone(ctx, host, project, pr.Milestone); err != nil {
+			errs = append(errs, fmt.Errorf("milestone: %v", err))
+		} else {
+			in["milestone_id"] = id
+		}
+	}
+
+	// a merge request from a fork is opened on the fork and targets the
+	// project, the created merge request belongs to the project though
+	source := project
+	if pr.Fork != "" {
+		source = gitlabProject(pr.Fork)
+		in["target_project_id"] = info.ID
+	}
+
+	var created gitlabMR
+	if err := g.call(ctx, host, http.MethodPost, source+"/merge_requests", in, &created); err != nil {
+		// a run which crashed after opening it, or a repeated one, finds
+		// the merge request of the branch already open
+		if !hasStatus(err, http.StatusConflict) {
+			return "", err
+		}
+		if created, err = g.openMR(ctx, host, project, pr.Head); err != nil {
+			return "", err
+		}
+		loggerFrom(ctx).Info("merge request already open", "url", created.WebURL)
+	}
+
+	if pr.AutoMerge != "" {
+		path := fmt.Sprintf("%s/merge_requests/%d/merge", project, created.IID)
+		if err := g.enableAutoMerge(ctx, host, path, pr.AutoMerge); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return created.WebURL, errors.Join(errs...)
+}
+
+type gitlabMR struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+// openMR returns the open merge request of the source branch targeting the
+// project.
+func (g *gitlab) openMR(ctx context.Context, host, project, branch string) (gitlabMR, error) {
+	var mrs []gitlabMR
+	path := project + "/merge_requests?state=opened&source_branch=" + url.QueryEscape(branch)
+	if err := g.call(ctx, host, http.MethodGet, path, nil, &mrs); err != nil {
+		return gitlabMR{}, err
+	}
+
+	if len(mrs) == 0 {
+		return gitlabMR{}, fmt.Errorf("%s: no open merge request for %s", project, branch)
+	}
+
+	return mrs[0], nil
+}
+
+// autoMergeRetry waits for the pipeline of a new merge request, which
+// GitLab starts a moment after the merge request is opened.
+var autoMergeRetry = Retry{Attempts: 5, Backoff: 2 * time.Second}
+
+// enableAutoMerge has GitLab merge the merge request once its pipeline
+// succeeds. GitLab refuses with 405 or 406 until the pipeline started, so
+// those are tried again for a while.
+func (g *gitlab) enableAutoMerge(ctx context.Context, host, path, method string) error {
+	merge := map[string]interface{}{
+		"merge_when_pipeline_succeeds": true,
+		"squash":                       method == mergeSquash,
+	}
+
+	err := retry(ctx, autoMergeRetry, "PUT "+path, func() error {
+		err := g.call(ctx, host, http.MethodPut, path, merge, nil)
+		if hasStatus(err, http.StatusMethodNotAllowed, http.StatusNotAcceptable) {
+			return transient(err, 0)
+		}
+		return err
+	})
+	if hasStatus(err, http.StatusMethodNotAllowed, http.StatusNotAcceptable) {
+		return fmt.Errorf("enabling auto-merge: no pipeline started for the merge request or it can't be merged: %v", err)
+	}
+
+	return err
+}
+
+// Fork returns the fork of the project origin points to in the namespace
+// owner, a user or group, creating it if there is none yet.
+func (g *gitlab) Fork(ctx context.Context, dir, owner string) (Fork, error) {
+	origin, err := git(ctx, dir, "remote", "get-url", "origin")
+	if err != nil {
+		return Fork{}, err
+	}
+	host, upstream := remoteHost(origin), remotePath(origin)
+
+	var project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		HTTPURLRepo       string `json:"http_url_to_repo"`
+		SSHURLRepo        string `json:"ssh_url_to_repo"`
+		ImportStatus      string `json:"import_status"`
+		ForkedFrom        *struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"forked_from_project"`
+	}
+	err = g.call(ctx, host, http.MethodGet, gitlabProject(forkPath(owner, upstream)), nil, &project)
+	if isNotFound(err) {
+		in := map[string]string{"namespace_path": owner}
+		if err := g.call(ctx, host, http.MethodPost, gitlabProject(upstream)+"/fork", in, &project); err != nil {
+			return Fork{}, err
+		}
+
+		// the repository is copied in the background
+		err = waitForFork(ctx, project.PathWithNamespace, func() (bool, error) {
+			if err := g.call(ctx, host, http.MethodGet, gitlabProject(project.PathWithNamespace), nil, &project); err != nil {
+				return false, err
+			}
+			if project.ImportStatus == "failed" {
+				return false, fmt.Errorf("forking %s to %s failed", upstream, owner)
+			}
+			return project.ImportStatus == "finished" || project.ImportStatus == "none", nil
+		})
+	}
+	if err != nil {
+		return Fork{}, err
+	}
+
+	if project.ForkedFrom == nil || !strings.EqualFold(project.ForkedFrom.PathWithNamespace, upstream) {
+		return Fork{}, fmt.Errorf("%s is not a fork of %s", project.PathWithNamespace, upstream)
+	}
+
+	return Fork{Path: project.PathWithNamespace, CloneURL: cloneURL(origin, project.HTTPURLRepo, project.SSHURLRepo)}, nil
+}
+
+// userIDs looks up the ids of the usernames, which is what GitLab takes.
+func (g *gitlab) userIDs(ctx context.Context, host string, names []string) ([]int, error) {
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		g.mu.Lock()
+		id, ok := g.users[host+"/"+name]
+		g.mu.Unlock()
+
+		if !ok {
+			var users []struct {
+				ID int `json:"id"`
+			}
+			if err := g.call(ctx, host, http.MethodGet, "users?username="+url.QueryEscape(name), nil, &users); err != nil {
+				return nil, err
+			}
+			if len(users) == 0 {
+				return nil, fmt.Errorf("%s: no user %q", host, name)
+			}
+			id = users[0].ID
+
+			g.mu.Lock()
+			g.users[host+"/"+name] = id
+			g.mu.Unlock()
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// milestone returns the id of the active milestone with the title, a
+// number is taken as the id.
+func (g *gitlab) milestone(ctx context.Context, host, project, title string) (int, error) {
+	if id, err := strconv.Atoi(title); err == nil {
+		return id, nil
+	}
+
+	var milestones []struct {
+		ID int `json:"id"`
+	}
+	path := fmt.Sprintf("%s/milestones?state=active&title=%s", project, url.QueryEscape(title))
+	if err := g.call(ctx, host, http.MethodGet, path, nil, &milestones); err != nil {
+		return 0, err
+	}
+
+	if len(milestones) == 0 {
+		return 0, fmt.Errorf("%s: no active milestone %q", project, title)
+	}
+
+	return milestones[0].ID, nil
+}
+
+// mrRef is a merge request parsed from its web URL,
+// https://gitlab.com/group/project/-/merge_requests/1.
+type mrRef struct {
+	host    string
+	project string
+	iid     int
+}
+
+func parseMRURL(raw string) (mrRef, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return mrRef{}, err
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/-/merge_requests/")
+	if len(parts) != 2 {
+		return mrRef{}, fmt.Errorf("not a merge request url: %s", raw)
+	}
+
+	iid, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return mrRef{}, fmt.Errorf("not a merge request url: %s", raw)
+	}
+
+	return mrRef{host: u.Host, project: gitlabProject(parts[0]), iid: iid}, nil
+}
+
+func (g *gitlab) PullRequest(ctx context.Context, url string) (PullRequest, error) {
+	ref, err := parseMRURL(url)
+	if err != nil {
+		return PullRequest{}, err
+	}
+
+	path := fmt.Sprintf("%s/merge_requests/%d", ref.project, ref.iid)
+	var mr struct {
+		State        string `json:"state"`
+		HasConflicts bool   `json:"has_conflicts"`
+		References   struct {
+			Full string `json:"full"`
+		} `json:"references"`
+		HeadPipeline *struct {
+			Status string `json:"status"`
+		} `json:"head_pipeline"`
+	}
+	if err := g.call(ctx, ref.host, http.MethodGet, path, nil, &mr); err != nil {
+		return PullRequest{}, err
+	}
+
+	result := PullRequest{
+		Repo:      strings.Split(mr.References.Full, "!")[0],
+		URL:       url,
+		State:     mr.State,
+		Checks:    checksNone,
+		Conflicts: mr.HasConflicts,
+	}
+	if mr.State == "opened" {
+		result.State = prOpen
+	}
+	if mr.HeadPipeline != nil {
+		switch mr.HeadPipeline.Status {
+		case "success", "skipped":
+			result.Checks = checksSuccess
+		case "failed", "canceled":
+			result.Checks = checksFailure
+		default:
+			result.Checks = checksPending
+		}
+	}
+
+	var approvals struct {
+		Approved   bool       `json:"approved"`
+		ApprovedBy []struct{} `json:"approved_by"`
+	}
+	if err := g.call(ctx, ref.host, http.MethodGet, path+"/approvals", nil, &approvals); err != nil {
+		return result, err
+	}
+	result.Review = reviewPending
+	if approvals.Approved && len(approvals.ApprovedBy) > 0 {
+		result.Review = reviewApproved
+	}
+
+	return result, nil
+}
+
+// Merge merges the merge request now. GitLab has no per merge request
+// method, squash squashes and the others use the project's merge method.
+func (g *gitlab) Merge(ctx context.Context, url, method string) error {
+	ref, err := parseMRURL(url)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("%s/merge_requests/%d/merge", ref.project, ref.iid)
+	return g.call(ctx, ref.host, http.MethodPut, path, map[string]bool{"squash": method == mergeSquash}, nil)
+}
+
+// Repositories lists the clone URLs of the active Go projects of a group
+// and its subgroups, `host/group` on a self-hosted GitLab.
+func (g *gitlab) Repositories(ctx context.Context, org string) ([]string, error) {
+	host, group := splitOrg("gitlab", org)
+
+	var urls []string
+	for page := 1; ; page++ {
+		var projects []struct {
+			ID          int    `json:"id"`
+			HTTPURLRepo string `json:"http_url_to_repo"`
+		}
+		path := fmt.Sprintf("groups/%s/projects?include_subgroups=true&archived=false&per_page=100&page=%d", url.PathEscape(group), page)
+		if err := g.call(ctx, host, http.MethodGet, path, nil, &projects); err != nil {
+			return nil, err
+		}
+
+		for _, p := range projects {
+			var languages map[string]float64
+			if err := g.call(ctx, host, http.MethodGet, fmt.Sprintf("projects/%d/languages", p.ID), nil, &languages); err != nil {
+				return nil, err
+			}
+			if _, ok := languages["Go"]; ok {
+				urls = append(urls, p.HTTPURLRepo)
+			}
+		}
+
+		if len(projects) < 100 {
+			return urls, nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGitLabEnableAutoMerge(t *testing.T) {
+	defer func(r Retry) { autoMergeRetry = r }(autoMergeRetry)
+	autoMergeRetry = Retry{Attempts: 3}
+
+	status := func(code int) *apiResponse {
+		return &apiResponse{status: code, header: http.Header{}}
+	}
+
+	tests := []struct {
+		name      string
+		responses []*apiResponse
+		calls     int
+		fails     bool
+	}{
+		{name: "enabled", calls: 1},
+		{name: "pipeline started late", responses: []*apiResponse{status(405), status(406)}, calls: 3},
+		{name: "no pipeline", responses: []*apiResponse{status(405), status(405), status(405)}, calls: 3, fails: true},
+		{name: "forbidden", responses: []*apiResponse{status(403)}, calls: 1, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{responses: tt.responses}
+			g := newGitLab(api, Retry{Attempts: 1})
+			err := g.enableAutoMerge(context.Background(), "gitlab.com", "projects/a%2Fb/merge_requests/1/merge", mergeSquash)
+			if (err != nil) != tt.fails {
+				t.Errorf("enableAutoMerge() = %v, want failure %v", err, tt.fails)
+			}
+			if api.calls != tt.calls {
+				t.Errorf("enableAutoMerge() sent %d requests, want %d", api.calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestGitLabOpenPullRequestExisting(t *testing.T) {
+	dir := originRepo(t, "git@gitlab.com:acme/sub/a.git")
+	respond := func(status int, body string) *apiResponse {
+		return &apiResponse{status: status, header: http.Header{}, body: []byte(body)}
+	}
+	project := respond(200, `{"id":1,"default_branch":"main"}`)
+	exists := respond(409, `{"message":["Another open merge request already exists for this source branch: !3"]}`)
+
+	tests := []struct {
+		name      string
+		responses []*apiResponse
+		url       string
+		fails     bool
+	}{
+		{
+			name:      "opened",
+			responses: []*apiResponse{project, respond(201, `{"iid":1,"web_url":"https://gitlab.com/acme/sub/a/-/merge_requests/1"}`)},
+			url:       "https://gitlab.com/acme/sub/a/-/merge_requests/1",
+		},
+		{
+			name:      "already open",
+			responses: []*apiResponse{project, exists, respond(200, `[{"iid":3,"web_url":"https://gitlab.com/acme/sub/a/-/merge_requests/3"}]`)},
+			url:       "https://gitlab.com/acme/sub/a/-/merge_requests/3",
+		},
+		{
+			name:      "already exists but merged",
+			responses: []*apiResponse{project, exists, respond(200, `[]`)},
+			fails:     true,
+		},
+		{
+			name:      "forbidden",
+			responses: []*apiResponse{project, respond(403, `{"message":"403 Forbidden"}`)},
+			fails:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{responses: tt.responses}
+			url, err := newGitLab(api, Retry{Attempts: 1}).OpenPullRequest(context.Background(), dir, NewPullRequest{Head: "next-Go"})
+			if (err != nil) != tt.fails || url != tt.url {
+				t.Errorf("OpenPullRequest() = %q, %v, want %q, failure %v", url, err, tt.url, tt.fails)
+			}
+			if len(api.requests) > 2 && api.requests[2] != "GET projects/acme%2Fsub%2Fa/merge_requests?state=opened&source_branch=next-Go" {
+				t.Errorf("OpenPullRequest() looked up %q", api.requests[2])
+			}
+		})
+	}
+}
+
+func TestGitLabOpenPullRequestOptionsFailing(t *testing.T) {
+	dir := originRepo(t, "git@gitlab.com:acme/a.git")
+	respond := func(status int, body string) *apiResponse {
+		return &apiResponse{status: status, header: http.Header{}, body: []byte(body)}
+	}
+
+	api := &fakeAPI{responses: []*apiResponse{
+		respond(200, `{"id":1,"default_branch":"main"}`),
+		respond(200, `[]`),
+		respond(200, `[{"id":7}]`),
+		respond(403, `{"message":"403 Forbidden"}`),
+		respond(201, `{"iid":1,"web_url":"https://gitlab.com/acme/a/-/merge_requests/1"}`),
+	}}
+	pr := NewPullRequest{Head: "next-Go", Reviewers: []string{"gone"}, Assignees: []string{"alice"}, Milestone: "Q3"}
+	url, err := newGitLab(api, Retry{Attempts: 1}).OpenPullRequest(context.Background(), dir, pr)
+	if url != "https://gitlab.com/acme/a/-/merge_requests/1" || err == nil {
+		t.Fatalf("OpenPullRequest() = %q, %v, want the merge request and the failed options", url, err)
+	}
+	for _, option := range []string{"reviewers", "milestone"} {
+		if !strings.Contains(err.Error(), option) {
+			t.Errorf("OpenPullRequest() error %q doesn't report the %s", err, option)
+		}
+	}
+
+	create := api.bodies[len(api.bodies)-1]
+	if !strings.Contains(create, `"assignee_ids":[7]`) || strings.Contains(create, "reviewer_ids") || strings.Contains(create, "milestone_id") {
+		t.Errorf("OpenPullRequest() created the merge request with %s", create)
+	}
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var goDirectiveRe = regexp.MustCompile(`(?m)^go[ \t]+([0-9][^\s/]*)`)
+
+var moduleDirectiveRe = regexp.MustCompile(`(?m)^module[ \t]+"?([^\s"]+)"?`)
+
+var toolchainDirectiveRe = regexp.MustCompile(`(?m)^toolchain[ \t]+go([0-9][^\s/]*)`)
+
+var toolchainLineRe = regexp.MustCompile(`(?m)\n*^toolchain[ \t]+\S+[ \t]*$`)
+
+// moduleDirective returns the module path declared in a go.mod file.
+func moduleDirective(mod []byte) string {
+	match := moduleDirectiveRe.FindSubmatch(mod)
+	if match == nil {
+		return ""
+	}
+
+	return string(match[1])
+}
+
+// goDirective returns the version of the `go` directive in a go.mod file.
+func goDirective(mod []byte) string {
+	match := goDirectiveRe.FindSubmatch(mod)
+	if match == nil {
+		return ""
+	}
+
+	return string(match[1])
+}
+
+// toolchainDirective returns the version of the `toolchain` directive in a
+// go.mod file, without its go prefix.
+func toolchainDirective(mod []byte) string {
+	match := toolchainDirectiveRe.FindSubmatch(mod)
+	if match == nil {
+		return ""
+	}
+
+	return string(match[1])
+}
+
+// setGoDirective rewrites the `go` directive leaving the rest of go.mod as is.
+func setGoDirective(mod []byte, version string) []byte {
+	return goDirectiveRe.ReplaceAll(mod, []byte("go "+version))
+}
+
+// setToolchainDirective rewrites the `toolchain` directive, an empty version
+// removes it. A go.mod without the directive is left as is.
+func setToolchainDirective(mod []byte, version string) []byte {
+	if version == "" {
+		return toolchainLineRe.ReplaceAll(mod, nil)
+	}
+
+	return toolchainDirectiveRe.ReplaceAll(mod, []byte("toolchain go"+version))
+}
+
+type requirement struct {
+	path     string
+	version  string
+	indirect bool
+}
+
+// requires lists the modules of the `require` directives in a go.mod file.
+func requires(mod []byte) []requirement {
+	var reqs []requirement
+	for _, d := range directives(mod, "require") {
+		if len(d.fields) != 2 {
+			continue
+		}
+		reqs = append(reqs, requirement{path: d.fields[0], version: d.fields[1], indirect: d.indirect})
+	}
+
+	return reqs
+}
+
+// replacement is a `replace` directive. An empty version on the left
+// replaces every version, a replacement without a version is a local
+// directory.
+type replacement struct {
+	path       string
+	version    string
+	newPath    string
+	newVersion string
+}
+
+// replaces lists the `replace` directives in a go.mod file.
+func replaces(mod []byte) []replacement {
+	var reps []replacement
+	for _, d := range directives(mod, "replace") {
+		var r replacement
+		switch {
+		case len(d.fields) == 3 && d.fields[1] == "=>":
+			r = replacement{path: d.fields[0], newPath: d.fields[2]}
+		case len(d.fields) == 4 && d.fields[1] == "=>":
+			r = replacement{path: d.fields[0], newPath: d.fields[2], newVersion: d.fields[3]}
+		case len(d.fields) == 4 && d.fields[2] == "=>":
+			r = replacement{path: d.fields[0], version: d.fields[1], newPath: d.fields[3]}
+		case len(d.fields) == 5 && d.fields[2] == "=>":
+			r = replacement{path: d.fields[0], version: d.fields[1], newPath: d.fields[3], newVersion: d.fields[4]}
+		default:
+			continue
+		}
+		reps = append(reps, r)
+	}
+
+	return reps
+}
+
+// replace applies the replace directives to a requirement, the last
+// matching one wins like in the go command.
+func replace(req requirement, reps []replacement) (replacement, bool) {
+	var found replacement
+	ok := false
+	for _, r := range reps {
+		if r.path == req.path && (r.version == "" || r.version == req.version) {
+			found, ok = r, true
+		}
+	}
+
+	return found, ok
+}
+
+type directive struct {
+	fields   []string
+	indirect bool
+}
+
+// directives returns the arguments of every directive of one kind, written
+// on a single line or in a block.
+func directives(mod []byte, verb string) []directive {
+	var found []directive
+	block := false
+	for _, line := range strings.Split(string(mod), "\n") {
+		line = strings.TrimSpace(line)
+		indirect := strings.HasSuffix(line, "// indirect")
+		if i := strings.Index(line, "//"); i != -1 {
+			line = strings.TrimSpace(line[:i])
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case block && line == ")":
+			block = false
+			continue
+		case len(fields) > 1 && fields[0] == verb && fields[1] == "(":
+			block = true
+			continue
+		case len(fields) > 1 && fields[0] == verb:
+			fields = fields[1:]
+		case !block:
+			continue
+		}
+
+		found = append(found, directive{fields: fields, indirect: indirect})
+	}
+
+	return found
+}
+
+// escapeModulePath applies the module cache case encoding, `Foo` -> `!foo`.
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
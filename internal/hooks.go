@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const (
+	hookPreEdit   = "pre-edit"
+	hookPostEdit  = "post-edit"
+	hookPreCommit = "pre-commit"
+	hookPostPR    = "post-pr"
+
+	policyAbort = "abort"
+	policyWarn  = "warn"
+)
+
+// Hooks are shell commands run in the repository directory around the bump.
+// A failing hook aborts the repository's bump unless the policy is `warn`,
+// post-pr hooks only warn as the pull request is open by then.
+type Hooks struct {
+	PreEdit   []string `yaml:"pre-edit"`
+	PostEdit  []string `yaml:"post-edit"`
+	PreCommit []string `yaml:"pre-commit"`
+	PostPR    []string `yaml:"post-pr"`
+	Policy    string   `yaml:"policy"`
+}
+
+func (h Hooks) commands(stage string) []string {
+	switch stage {
+	case hookPreEdit:
+		return h.PreEdit
+	case hookPostEdit:
+		return h.PostEdit
+	case hookPreCommit:
+		return h.PreCommit
+	case hookPostPR:
+		return h.PostPR
+	}
+
+	return nil
+}
+
+type hookEnv struct {
+	repo string
+	from string
+	to   string
+	pr   string
+}
+
+// runHooks runs the global hooks of a stage followed by the repository's.
+func (w *Worker) runHooks(ctx context.Context, stage, path string, env hookEnv) error {
+	policy := w.cfg.Hooks.Policy
+	if w.repoCfg.Hooks.Policy != "" {
+		policy = w.repoCfg.Hooks.Policy
+	}
+
+	commands := append(append([]string(nil), w.cfg.Hooks.commands(stage)...), w.repoCfg.Hooks.commands(stage)...)
+	for _, command := range commands {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = filepath.Join(path)
+		cmd.Env = append(os.Environ(),
+			"GOBUMP_STAGE="+stage,
+			"GOBUMP_REPO="+env.repo,
+			"GOBUMP_FROM="+env.from,
+			"GOBUMP_TO="+env.to,
+			"GOBUMP_PR_URL="+env.pr,
+		)
+
+		output, err := run(ctx, cmd)
+		if err == nil {
+			continue
+		}
+
+		err = fmt.Errorf("%s hook %q: %v\n%s", stage, command, err, output)
+		if policy != policyWarn {
+			return err
+		}
+		w.log.Warn("hook failed", "stage", stage, "err", err)
+	}
+
+	return nil
+}
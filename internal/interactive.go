@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// reviewer serializes the per repository prompts of the interactive mode,
+// repositories keep being prepared in the background meanwhile.
+type reviewer struct {
+	mu   sync.Mutex
+	in   *bufio.Reader
+	out  io.Writer
+	quit bool
+}
+
+func newReviewer(in io.Reader, out io.Writer) *reviewer {
+	return &reviewer{in: bufio.NewReader(in), out: out}
+}
+
+func (r *reviewer) quitting() bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.quit
+}
+
+// review shows the planned change and its verification and asks whether to
+// apply it. Skipped changes are reverted.
+func (w *Worker) review(ctx context.Context, name, path string, verifyErr error) (bool, error) {
+	r := w.reviewer
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for !r.quit {
+		diff, err := git(ctx, path, "diff", "--stat", "--patch")
+		if err != nil {
+			return false, err
+		}
+		untracked, err := git(ctx, path, "ls-files", "--others", "--exclude-standard", "--directory")
+		if err != nil {
+			return false, err
+		}
+
+		fmt.Fprintf(r.out, "\n=== %s: go %s -> %s\n%s\n", name, w.currentGo, w.version, diff)
+		if untracked != "" {
+			fmt.Fprintf(r.out, "new files:\n%s\n", untracked)
+		}
+		if verifyErr != nil {
+			fmt.Fprintf(r.out, "verification FAILED: %v\n", verifyErr)
+		} else {
+			fmt.Fprintln(r.out, "verification passed")
+		}
+
+		fmt.Fprint(r.out, "[a]pply / [s]kip / [e]dit / [q]uit: ")
+		answer, err := r.in.ReadString('\n')
+		if err != nil && answer == "" {
+			return false, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "a", "apply":
+			return true, nil
+		case "s", "skip":
+			return false, revert(ctx, path)
+		case "e", "edit":
+			if err := openEditor(ctx, path); err != nil {
+				fmt.Fprintln(r.out, "edit:", err)
+			}
+			verifyErr = w.verify(ctx, path)
+		case "q", "quit":
+			r.quit = true
+		}
+	}
+
+	return false, revert(ctx, path)
+}
+
+// openEditor opens $VISUAL or $EDITOR on the changed files.
+func openEditor(ctx context.Context, path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	changed, err := git(ctx, path, "diff", "--name-only", "-z")
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, "editor")
+	for _, name := range strings.Split(changed, "\x00") {
+		if name != "" {
+			cmd.Args = append(cmd.Args, name)
+		}
+	}
+	cmd.Dir = path
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// revert drops the changes and the new files of the bump, the tree was
+// clean before it, see apply.
+func revert(ctx context.Context, path string) error {
+	if _, err := git(ctx, path, "checkout", "--", "."); err != nil {
+		return err
+	}
+
+	_, err := git(ctx, path, "clean", "-d", "--force")
+	return err
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// changedRepo is a repository with a committed go.mod, changed since, and a
+// new untracked file.
+func changedRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{goMod: "module x\n\ngo 1.21\n"})
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+	writeFiles(t, dir, map[string]string{goMod: "module x\n\ngo 1.22\n", "new.go": "package x\n"})
+
+	return dir
+}
+
+func TestReview(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		verifyErr error
+		apply     bool
+		fails     bool
+		quit      bool
+		mod       string
+		prompts   int
+		output    string
+	}{
+		{name: "apply", input: "a\n", apply: true, mod: "go 1.22", prompts: 1, output: "verification passed"},
+		{name: "apply spelled out", input: " Apply \n", apply: true, mod: "go 1.22", prompts: 1},
+		{name: "skip", input: "s\n", mod: "go 1.21", prompts: 1},
+		{name: "unknown answer asks again", input: "x\na\n", apply: true, mod: "go 1.22", prompts: 2},
+		{name: "quit", input: "q\n", quit: true, mod: "go 1.21", prompts: 1},
+		{
+			name:      "edit verifies again",
+			input:     "e\na\n",
+			verifyErr: errors.New("go test: exit status 1"),
+			apply:     true,
+			mod:       "go 1.23",
+			prompts:   2,
+			output:    "verification FAILED: go test: exit status 1",
+		},
+		{name: "end of input", input: "", fails: true, mod: "go 1.22", prompts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", "sed -i s/1.22/1.23/")
+			dir := changedRepo(t)
+
+			var out bytes.Buffer
+			w := NewWorker(dir, Config{Version: "1.22"})
+			w.reviewer = newReviewer(strings.NewReader(tt.input), &out)
+			apply, err := w.review(context.Background(), "a", dir, tt.verifyErr)
+			if apply != tt.apply || (err != nil) != tt.fails {
+				t.Fatalf("review() = %v, %v, want %v, failure %v", apply, err, tt.apply, tt.fails)
+			}
+			if w.reviewer.quitting() != tt.quit {
+				t.Errorf("quitting() = %v, want %v", w.reviewer.quitting(), tt.quit)
+			}
+
+			if read, _ := ioutil.ReadFile(filepath.Join(dir, goMod)); !strings.Contains(string(read), tt.mod) {
+				t.Errorf("go.mod =\n%s\nwant %s", read, tt.mod)
+			}
+			_, err = os.Stat(filepath.Join(dir, "new.go"))
+			if reverted := os.IsNotExist(err); reverted != (!tt.apply && !tt.fails) {
+				t.Errorf("new file removed %v, want %v", reverted, !tt.apply && !tt.fails)
+			}
+
+			if got := strings.Count(out.String(), "[a]pply / [s]kip / [e]dit / [q]uit"); got != tt.prompts {
+				t.Errorf("review() prompted %d times, want %d\n%s", got, tt.prompts, out.String())
+			}
+			if !strings.Contains(out.String(), "=== a: go") || !strings.Contains(out.String(), "new.go") {
+				t.Errorf("review() didn't show the change\n%s", out.String())
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("review() output lacks %q\n%s", tt.output, out.String())
+			}
+		})
+	}
+}
+
+func TestReviewQuitLeavesRepositoryPending(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewCampaign(dir, "c", "", nil, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWorker(dir, Config{Version: "1.22"})
+	w.UseCampaign(c)
+	w.reviewer = newReviewer(strings.NewReader(""), ioutil.Discard)
+	w.reviewer.quit = true
+
+	res := w.applyRepo(context.Background(), RepoPlan{Name: "a", From: "1.21", To: "1.22"})
+	if res.Status != statusSkipped || res.Reason != "review quit" || res.Err != nil {
+		t.Errorf("applyRepo() = %+v, want skipped by review quit", res)
+	}
+	if state := c.state("a"); state.Status != statusPending || c.completed("a") {
+		t.Errorf("campaign state = %+v, want pending", state)
+	}
+}
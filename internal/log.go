@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Log configures the logging. Dir receives a file per repository with the
+// full output of every command run for it.
+type Log struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+	Dir    string `yaml:"dir"`
+}
+
+// NewLogger builds a logger writing to out in the configured level and
+// format, text or json. Tokens are redacted from every record.
+func NewLogger(cfg Log, out io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, fmt.Errorf("log level %q: %v", cfg.Level, err)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch cfg.Format {
+	case "", "text":
+		return slog.New(redactHandler{slog.NewTextHandler(out, opts)}), nil
+	case "json":
+		return slog.New(redactHandler{slog.NewJSONHandler(out, opts)}), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+}
+
+type commandLogKey struct{}
+
+// commandLog is where the commands of a repository are logged, the file is
+// nil without a log directory.
+type commandLog struct {
+	logger *slog.Logger
+	mu     sync.Mutex
+	file   io.Writer
+}
+
+// openLog returns a context carrying the repository's command log and a
+// func closing it. The log file is appended to, planning and applying
+// share it.
+func (w *Worker) openLog(ctx context.Context, name string) (context.Context, func(), error) {
+	cl := &commandLog{logger: w.log}
+	if w.cfg.Log.Dir == "" {
+		return context.WithValue(ctx, commandLogKey{}, cl), func() {}, nil
+	}
+
+	path := filepath.Join(w.cfg.Log.Dir, name+".log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return ctx, nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return ctx, nil, err
+	}
+	cl.file = f
+
+	return context.WithValue(ctx, commandLogKey{}, cl), func() { f.Close() }, nil
+}
+
+// logCommand records a finished command at debug level and appends its
+// output to the repository's log file.
+func logCommand(ctx context.Context, cmd *exec.Cmd, output []byte, err error) {
+	cl, ok := ctx.Value(commandLogKey{}).(*commandLog)
+	if !ok {
+		return
+	}
+
+	line := strings.Join(cmd.Args, " ")
+	if err != nil {
+		cl.logger.Debug("command failed", "cmd", line, "dir", cmd.Dir, "err", err)
+	} else {
+		cl.logger.Debug("command", "cmd", line, "dir", cmd.Dir)
+	}
+
+	if cl.file == nil {
+		return
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cmd.Dir != "" {
+		line += " (in " + cmd.Dir + ")"
+	}
+	fmt.Fprintf(cl.file, "$ %s\n%s", Redact(line), Redact(string(output)))
+	if err != nil {
+		fmt.Fprintf(cl.file, "# %s\n", Redact(err.Error()))
+	}
+}
+
+// logAPICall records a provider API call at debug level and appends the
+// request and the response to the repository's log file.
+func logAPICall(ctx context.Context, host, method, path string, body []byte, resp *apiResponse, err error) {
+	call := method + " " + host + "/" + strings.TrimPrefix(path, "/")
+	switch {
+	case err != nil:
+		loggerFrom(ctx).Debug("api call failed", "call", call, "err", err)
+	default:
+		loggerFrom(ctx).Debug("api call", "call", call, "status", resp.status)
+	}
+
+	cl, ok := ctx.Value(commandLogKey{}).(*commandLog)
+	if !ok || cl.file == nil {
+		return
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	fmt.Fprintf(cl.file, "> %s\n", Redact(call))
+	if len(body) > 0 {
+		fmt.Fprintf(cl.file, "%s\n", Redact(string(body)))
+	}
+	if err != nil {
+		fmt.Fprintf(cl.file, "# %s\n", Redact(err.Error()))
+		return
+	}
+	fmt.Fprintf(cl.file, "< %d\n%s\n", resp.status, Redact(string(resp.body)))
+}
+
+// loggerFrom returns the logger of the repository of the context.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if cl, ok := ctx.Value(commandLogKey{}).(*commandLog); ok {
+		return cl.logger
+	}
+
+	return slog.Default()
+}
+
+// run runs the command and returns its combined output, which is logged
+// with the repository of the context.
+func run(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	output, err := cmd.CombinedOutput()
+	logCommand(ctx, cmd, output, err)
+	return output, err
+}
+
+// output runs a command whose stdout is parsed, stderr ends up in the log
+// and in the error.
+func output(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.Output()
+	logCommand(ctx, cmd, append(append([]byte(nil), stdout...), stderr.Bytes()...), err)
+	if err != nil {
+		return stdout, fmt.Errorf("%v\n%s", err, stderr.Bytes())
+	}
+
+	return stdout, nil
+}
@@ -0,0 +1,426 @@
+package internal
+
+import (
+	"bytes"
+	"go/ast"
+	"go/build/constraint"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ioutilReplacements maps the io/ioutil functions to their drop-in
+// replacements. ReadDir is left alone as os.ReadDir returns DirEntry values.
+var ioutilReplacements = map[string][2]string{
+	"ReadAll":   {"io", "ReadAll"},
+	"NopCloser": {"io", "NopCloser"},
+	"Discard":   {"io", "Discard"},
+	"ReadFile":  {"os", "ReadFile"},
+	"WriteFile": {"os", "WriteFile"},
+	"TempFile":  {"os", "CreateTemp"},
+	"TempDir":   {"os", "MkdirTemp"},
+}
+
+type edit struct {
+	start int
+	end   int
+	text  string
+}
+
+type modernizer struct {
+	fset  *token.FileSet
+	file  *ast.File
+	src   []byte
+	edits []edit
+}
+
+// modernizeEditor rewrites go sources to use the features unlocked
+// between the old and the new version.
+type modernizeEditor struct{}
+
+func (modernizeEditor) Match(file string) bool {
+	return path.Ext(file) == ".go"
+}
+
+func (modernizeEditor) Edit(file string, content []byte, from, to string) ([]byte, error) {
+	modernized, ok := modernizeSource(content, from, to)
+	if !ok {
+		return content, nil
+	}
+
+	return modernized, nil
+}
+
+func (modernizeEditor) Summary(from, to string) string {
+	return "modernized for go " + to
+}
+
+// modernizeSource applies every rewrite unlocked between the from and to
+// versions. Sources that don't parse are reported as not modernized.
+func modernizeSource(src []byte, from, to string) ([]byte, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return src, false
+	}
+
+	m := modernizer{fset: fset, file: file, src: src}
+	if crosses(from, to, "1.16") {
+		m.ioutil()
+	}
+	if crosses(from, to, "1.17") {
+		m.buildTags()
+	}
+	if crosses(from, to, "1.18") {
+		m.emptyInterfaces()
+	}
+	if crosses(from, to, "1.22") {
+		m.loopCopies()
+	}
+
+	if len(m.edits) == 0 {
+		return src, true
+	}
+
+	formatted, err := format.Source(m.apply())
+	if err != nil {
+		return src, false
+	}
+
+	return formatted, true
+}
+
+// crosses reports whether moving from one version to another passes the
+// release that introduced a feature.
+func crosses(from, to, release string) bool {
+	return compareVersions(from, release) < 0 && compareVersions(to, release) >= 0
+}
+
+func (m *modernizer) offset(pos token.Pos) int {
+	return m.fset.Position(pos).Offset
+}
+
+func (m *modernizer) replace(start, end token.Pos, text string) {
+	m.edits = append(m.edits, edit{start: m.offset(start), end: m.offset(end), text: text})
+}
+
+// removeLine drops the node together with its line when nothing else but
+// indentation precedes it.
+func (m *modernizer) removeLine(node ast.Node) {
+	start, end := m.offset(node.Pos()), m.offset(node.End())
+	lineStart := bytes.LastIndexByte(m.src[:start], '\n') + 1
+	if len(bytes.TrimSpace(m.src[lineStart:start])) == 0 {
+		start = lineStart
+		if nl := bytes.IndexByte(m.src[end:], '\n'); nl != -1 {
+			end += nl + 1
+		} else {
+			end = len(m.src)
+		}
+	}
+	m.edits = append(m.edits, edit{start: start, end: end})
+}
+
+func (m *modernizer) apply() []byte {
+	sort.Slice(m.edits, func(i, j int) bool {
+		return m.edits[i].start > m.edits[j].start
+	})
+
+	out := append([]byte(nil), m.src...)
+	last := len(out) + 1
+	for _, e := range m.edits {
+		// overlapping edits are dropped, the outer one wins
+		if e.end > last {
+			continue
+		}
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+		last = e.start
+	}
+
+	return out
+}
+
+// importName returns the name a package is referred to in the file, or an
+// empty string when it isn't imported.
+func (m *modernizer) importName(path string) (string, *ast.ImportSpec) {
+	for _, spec := range m.file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || p != path {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name, spec
+		}
+		return path[strings.LastIndex(path, "/")+1:], spec
+	}
+
+	return "", nil
+}
+
+func (m *modernizer) ioutil() {
+	name, spec := m.importName("io/ioutil")
+	if spec == nil || name == "_" || name == "." {
+		return
+	}
+
+	names := map[string]string{}
+	needed := map[string]bool{}
+	for _, pkg := range []string{"io", "os"} {
+		names[pkg], _ = m.importName(pkg)
+		if names[pkg] == "" {
+			names[pkg] = pkg
+		}
+	}
+
+	remaining := 0
+	ast.Inspect(m.file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok || ident.Name != name || ident.Obj != nil {
+			return true
+		}
+
+		replacement, ok := ioutilReplacements[sel.Sel.Name]
+		if !ok {
+			remaining++
+			return false
+		}
+
+		pkg := replacement[0]
+		if existing, _ := m.importName(pkg); existing == "" {
+			needed[pkg] = true
+		}
+		m.replace(sel.Pos(), sel.End(), names[pkg]+"."+replacement[1])
+		return false
+	})
+
+	var imports []string
+	for _, pkg := range []string{"io", "os"} {
+		if needed[pkg] {
+			imports = append(imports, strconv.Quote(pkg))
+		}
+	}
+
+	decl, block := m.importDecl(spec), m.importBlock()
+	switch {
+	case len(imports) == 0 && remaining > 0:
+	case len(imports) == 0 && decl.Lparen.IsValid():
+		m.removeLine(spec)
+	case len(imports) == 0:
+		m.removeLine(decl)
+	case decl.Lparen.IsValid() && remaining > 0:
+		m.replace(spec.End(), spec.End(), "\n"+strings.Join(imports, "\n"))
+	case decl.Lparen.IsValid():
+		m.replace(spec.Pos(), spec.End(), strings.Join(imports, "\n"))
+	case block != nil:
+		// format sorts the new imports into the first group of the block
+		m.replace(block.Specs[0].Pos(), block.Specs[0].Pos(), strings.Join(imports, "\n")+"\n")
+		if remaining == 0 {
+			m.removeLine(decl)
+		}
+	case remaining > 0:
+		m.replace(decl.Pos(), decl.End(), "import (\n"+string(m.src[m.offset(spec.Pos()):m.offset(spec.End())])+"\n"+strings.Join(imports, "\n")+"\n)")
+	case len(imports) == 1:
+		m.replace(decl.Pos(), decl.End(), "import "+imports[0])
+	default:
+		m.replace(decl.Pos(), decl.End(), "import (\n"+strings.Join(imports, "\n")+"\n)")
+	}
+}
+
+// importBlock returns the first parenthesized import declaration, new
+// imports are merged into it.
+func (m *modernizer) importBlock() *ast.GenDecl {
+	for _, decl := range m.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if ok && gen.Tok == token.IMPORT && gen.Lparen.IsValid() && len(gen.Specs) > 0 {
+			return gen
+		}
+	}
+
+	return nil
+}
+
+func (m *modernizer) importDecl(spec *ast.ImportSpec) *ast.GenDecl {
+	for _, decl := range m.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, s := range gen.Specs {
+			if s == spec {
+				return gen
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildTags replaces `// +build` lines with a single `//go:build` line, or
+// simply drops them when the file already carries one.
+func (m *modernizer) buildTags() {
+	var plus []*ast.Comment
+	hasGoBuild := false
+	for _, group := range m.file.Comments {
+		if group.Pos() > m.file.Package {
+			break
+		}
+		for _, c := range group.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				hasGoBuild = true
+			case constraint.IsPlusBuild(c.Text):
+				plus = append(plus, c)
+			}
+		}
+	}
+
+	if len(plus) == 0 {
+		return
+	}
+
+	var expr constraint.Expr
+	for _, c := range plus {
+		parsed, err := constraint.Parse(c.Text)
+		if err != nil {
+			return
+		}
+		if expr == nil {
+			expr = parsed
+		} else {
+			expr = &constraint.AndExpr{X: expr, Y: parsed}
+		}
+	}
+
+	for i, c := range plus {
+		if i == 0 && !hasGoBuild {
+			m.replace(c.Pos(), c.End(), "//go:build "+expr.String())
+			continue
+		}
+		m.removeLine(c)
+	}
+}
+
+func (m *modernizer) emptyInterfaces() {
+	shadowed := false
+	ast.Inspect(m.file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == "any" && ident.Obj != nil {
+			shadowed = true
+		}
+		return !shadowed
+	})
+	if shadowed {
+		return
+	}
+
+	ast.Inspect(m.file, func(n ast.Node) bool {
+		iface, ok := n.(*ast.InterfaceType)
+		if ok && len(iface.Methods.List) == 0 && !m.hasComments(iface) {
+			m.replace(iface.Pos(), iface.End(), "any")
+		}
+		return true
+	})
+}
+
+func (m *modernizer) hasComments(node ast.Node) bool {
+	for _, group := range m.file.Comments {
+		if group.Pos() >= node.Pos() && group.End() <= node.End() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loopCopies removes `x := x` copies of loop variables which are per
+// iteration since go 1.22.
+func (m *modernizer) loopCopies() {
+	ast.Inspect(m.file, func(n ast.Node) bool {
+		switch loop := n.(type) {
+		case *ast.RangeStmt:
+			if loop.Tok != token.DEFINE {
+				return true
+			}
+			vars := map[string]bool{}
+			for _, expr := range []ast.Expr{loop.Key, loop.Value} {
+				if ident, ok := expr.(*ast.Ident); ok && ident.Name != "_" {
+					vars[ident.Name] = true
+				}
+			}
+			m.removeCopies(loop.Body, vars, false)
+		case *ast.ForStmt:
+			init, ok := loop.Init.(*ast.AssignStmt)
+			if !ok || init.Tok != token.DEFINE {
+				return true
+			}
+			vars := map[string]bool{}
+			for _, expr := range init.Lhs {
+				if ident, ok := expr.(*ast.Ident); ok && ident.Name != "_" {
+					vars[ident.Name] = true
+				}
+			}
+			m.removeCopies(loop.Body, vars, true)
+		}
+		return true
+	})
+}
+
+// removeCopies drops the copies at the top level of the loop body. In three
+// clause loops the copy is only redundant when the body never writes the
+// variable, as writes would otherwise leak into the next iteration.
+func (m *modernizer) removeCopies(body *ast.BlockStmt, vars map[string]bool, strict bool) {
+	for _, stmt := range body.List {
+		assign, ok := stmt.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != len(assign.Rhs) {
+			continue
+		}
+
+		redundant := true
+		for i := range assign.Lhs {
+			lhs, ok := assign.Lhs[i].(*ast.Ident)
+			rhs, ok2 := assign.Rhs[i].(*ast.Ident)
+			if !ok || !ok2 || lhs.Name != rhs.Name || !vars[lhs.Name] ||
+				(strict && writes(body, lhs.Name)) {
+				redundant = false
+				break
+			}
+		}
+
+		if redundant {
+			m.removeLine(assign)
+		}
+	}
+}
+
+func writes(body *ast.BlockStmt, name string) bool {
+	found := false
+	isName := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		return ok && ident.Name == name
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE {
+				return true
+			}
+			for _, lhs := range node.Lhs {
+				found = found || isName(lhs)
+			}
+		case *ast.IncDecStmt:
+			found = found || isName(node.X)
+		case *ast.UnaryExpr:
+			found = found || (node.Op == token.AND && isName(node.X))
+		}
+		return !found
+	})
+
+	return found
+}
@@ -0,0 +1,260 @@
+package internal
+
+import "testing"
+
+func TestModernizeSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		src      string
+		want     string
+	}{
+		{
+			name: "ioutil",
+			from: "1.15", to: "1.16",
+			src: `package p
+
+import "io/ioutil"
+
+func f() ([]byte, error) {
+	if err := ioutil.WriteFile("a", nil, 0644); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile("a")
+}
+`,
+			want: `package p
+
+import "os"
+
+func f() ([]byte, error) {
+	if err := os.WriteFile("a", nil, 0644); err != nil {
+		return nil, err
+	}
+	return os.ReadFile("a")
+}
+`,
+		},
+		{
+			name: "ioutil ReadDir is kept",
+			from: "1.15", to: "1.16",
+			src: `package p
+
+import "io/ioutil"
+
+func f() {
+	ioutil.ReadDir(".")
+	ioutil.ReadAll(nil)
+}
+`,
+			want: `package p
+
+import (
+	"io"
+	"io/ioutil"
+)
+
+func f() {
+	ioutil.ReadDir(".")
+	io.ReadAll(nil)
+}
+`,
+		},
+		{
+			name: "ioutil in an import block",
+			from: "1.15", to: "1.16",
+			src: `package p
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"example.com/x"
+)
+
+func f() {
+	b, _ := ioutil.ReadAll(x.R)
+	ioutil.WriteFile("a", b, 0644)
+	fmt.Println(b)
+}
+`,
+			want: `package p
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"example.com/x"
+)
+
+func f() {
+	b, _ := io.ReadAll(x.R)
+	os.WriteFile("a", b, 0644)
+	fmt.Println(b)
+}
+`,
+		},
+		{
+			name: "ioutil kept in an import block",
+			from: "1.15", to: "1.16",
+			src: `package p
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+func f() {
+	ioutil.ReadDir(".")
+	ioutil.ReadAll(strings.NewReader(""))
+}
+`,
+			want: `package p
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+func f() {
+	ioutil.ReadDir(".")
+	io.ReadAll(strings.NewReader(""))
+}
+`,
+		},
+		{
+			name: "ioutil imported apart from the block",
+			from: "1.15", to: "1.16",
+			src: `package p
+
+import "io/ioutil"
+
+import (
+	"fmt"
+)
+
+func f() {
+	fmt.Println(ioutil.ReadFile("a"))
+}
+`,
+			want: `package p
+
+import (
+	"fmt"
+	"os"
+)
+
+func f() {
+	fmt.Println(os.ReadFile("a"))
+}
+`,
+		},
+		{
+			name: "ioutil and os already imported",
+			from: "1.15", to: "1.16",
+			src: `package p
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+func f() {
+	ioutil.WriteFile(os.DevNull, nil, 0644)
+}
+`,
+			want: `package p
+
+import (
+	"os"
+)
+
+func f() {
+	os.WriteFile(os.DevNull, nil, 0644)
+}
+`,
+		},
+		{
+			name: "build tags",
+			from: "1.16", to: "1.17",
+			src: `// +build linux,amd64 !cgo
+
+package p
+`,
+			want: `//go:build (linux && amd64) || !cgo
+
+package p
+`,
+		},
+		{
+			name: "empty interface",
+			from: "1.17", to: "1.18",
+			src: `package p
+
+func f(v interface{}) map[string]interface{} { return nil }
+`,
+			want: `package p
+
+func f(v any) map[string]any { return nil }
+`,
+		},
+		{
+			name: "loop variable copy",
+			from: "1.21", to: "1.22",
+			src: `package p
+
+func f(xs []int) {
+	for _, x := range xs {
+		x := x
+		go func() { _ = x }()
+	}
+}
+`,
+			want: `package p
+
+func f(xs []int) {
+	for _, x := range xs {
+		go func() { _ = x }()
+	}
+}
+`,
+		},
+		{
+			name: "nothing crossed",
+			from: "1.18", to: "1.21",
+			src: `package p
+
+func f(v interface{}) {}
+`,
+			want: `package p
+
+func f(v interface{}) {}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := modernizeSource([]byte(tt.src), tt.from, tt.to)
+			if !ok {
+				t.Fatalf("modernizeSource() not modernized")
+			}
+			if string(got) != tt.want {
+				t.Errorf("modernizeSource() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModernizeSourceInvalid(t *testing.T) {
+	src := []byte("package p\n\nfunc f( {\n")
+	got, ok := modernizeSource(src, "1.15", "1.22")
+	if ok {
+		t.Errorf("modernizeSource() of invalid source reported as modernized")
+	}
+	if string(got) != string(src) {
+		t.Errorf("modernizeSource() changed invalid source")
+	}
+}
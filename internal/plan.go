@@ -0,0 +1,537 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gammazero/workerpool"
+)
+
+// Plan is the change a bump makes to every repository, worked out without
+// touching them. It carries the config it was made with so it can be
+// written to a file and applied later on.
+type Plan struct {
+	Version string     `json:"version"`
+	Config  Config     `json:"config"`
+	Repos   []RepoPlan `json:"repos"`
+}
+
+// RepoPlan is the planned change of one repository. A repository with a
+// Skip reason is left alone, one with an Error can't be bumped. Policy is
+// the target of the version policy deciding To, Complies is set when the
+// repository is on its target already and Above when it is above the
+// version of a pin or exact target, which it violates.
+//
+// Besides writing the Edits, apply runs the dependency update, vendoring
+// and hooks listed in Steps. Their outcome can't be planned, so instead
+// Inputs holds the sha256 of the files they depend on, empty for missing
+// ones, and Base the commit a clone or worktree was planned at.
+type RepoPlan struct {
+	Name     string            `json:"name"`
+	Dir      string            `json:"dir,omitempty"`
+	Remote   string            `json:"remote,omitempty"`
+	Module   string            `json:"module,omitempty"`
+	From     string            `json:"from,omitempty"`
+	To       string            `json:"to,omitempty"`
+	Policy   string            `json:"policy,omitempty"`
+	Complies bool              `json:"complies,omitempty"`
+	Above    bool              `json:"above,omitempty"`
+	Skip     string            `json:"skip,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Edits    []FileEdit        `json:"edits,omitempty"`
+	Steps    []string          `json:"steps,omitempty"`
+	Inputs   map[string]string `json:"inputs,omitempty"`
+	Base     string            `json:"base,omitempty"`
+	Branch   string            `json:"branch,omitempty"`
+	Commit   string            `json:"commit,omitempty"`
+	Title    string            `json:"title,omitempty"`
+}
+
+// Violates reports whether the repository breaks its policy or version:
+// it is behind its target or above a pin or exact target. Repositories
+// skipped for another reason or failing to plan don't count.
+func (rp RepoPlan) Violates() bool {
+	return rp.Above || (rp.Skip == "" && rp.Error == "" && !rp.Complies)
+}
+
+// FileEdit is the new content of a file, the path is relative to the
+// repository root and slash separated. Before and After are the sha256 of
+// the content the edit was planned against and of the new content, Summary
+// describes the change in the pull request.
+type FileEdit struct {
+	Path    string `json:"path"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Summary string `json:"summary,omitempty"`
+	Content string `json:"content"`
+}
+
+// planInputs are the files the dependency update, vendoring and hooks
+// depend on besides the edited ones.
+var planInputs = []string{goMod, "go.sum", repoConfigFile, "vendor/modules.txt"}
+
+func contentHash(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// fileHash is the contentHash of a file, empty when it doesn't exist.
+func fileHash(path string) string {
+	read, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return contentHash(read)
+}
+
+// ReadPlan reads a plan written by Write.
+func ReadPlan(path string) (*Plan, error) {
+	read, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	if err := json.Unmarshal(read, plan); err != nil {
+		return nil, fmt.Errorf("plan %s: %v", path, err)
+	}
+
+	for _, rp := range plan.Repos {
+		for _, e := range rp.Edits {
+			if contentHash([]byte(e.Content)) != e.After {
+				return nil, fmt.Errorf("plan %s: %s/%s doesn't match its hash", path, rp.Name, e.Path)
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// Write saves the plan as indented JSON.
+func (p *Plan) Write(path string) error {
+	read, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(read, '\n'), 0644)
+}
+
+// Check compares the files of the local checkouts with the ones the plan
+// was made against, and the default branch of the remote with the commit
+// clones and worktrees were planned at.
+func (p *Plan) Check(ctx context.Context) error {
+	var changed []string
+	for _, rp := range p.Repos {
+		if rp.Skip != "" || rp.Error != "" {
+			continue
+		}
+
+		var err error
+		switch {
+		case rp.Remote != "":
+			err = checkRemote(ctx, "", rp.Remote, rp.Base)
+		case p.Config.Worktree:
+			if err = checkRemote(ctx, rp.Dir, "origin", rp.Base); err != nil {
+				err = fmt.Errorf("%s: %v", rp.Dir, err)
+			}
+		default:
+			err = checkPlanned(rp.Dir, rp)
+		}
+		if err != nil {
+			changed = append(changed, Redact(err.Error()))
+		}
+	}
+
+	if len(changed) > 0 {
+		return fmt.Errorf("the plan is stale:\n\t%s", strings.Join(changed, "\n\t"))
+	}
+
+	return nil
+}
+
+// checkRemote makes sure the default branch of the remote is still at the
+// commit the repository was planned at.
+func checkRemote(ctx context.Context, dir, remote, base string) error {
+	if base == "" {
+		return nil
+	}
+
+	output, err := git(ctx, dir, "ls-remote", remote, "HEAD")
+	if err != nil {
+		return err
+	}
+	if fields := strings.Fields(output); len(fields) == 0 || fields[0] != base {
+		return fmt.Errorf("the default branch of %s moved on since planning", remote)
+	}
+
+	return nil
+}
+
+// checkPlanned makes sure every edited file and every input of the apply
+// steps still has the content the repository was planned against.
+func checkPlanned(dir string, rp RepoPlan) error {
+	if err := checkEdits(dir, rp.Edits); err != nil {
+		return err
+	}
+
+	var changed []string
+	for _, path := range planInputs {
+		if want, ok := rp.Inputs[path]; ok && fileHash(filepath.Join(dir, filepath.FromSlash(path))) != want {
+			changed = append(changed, path)
+		}
+	}
+
+	if len(changed) > 0 {
+		return fmt.Errorf("%s: changed since planning: %s", dir, strings.Join(changed, ", "))
+	}
+
+	return nil
+}
+
+// checkEdits makes sure every file still has the content it was planned
+// against.
+func checkEdits(dir string, edits []FileEdit) error {
+	var changed []string
+	for _, e := range edits {
+		read, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(e.Path)))
+		if err != nil || contentHash(read) != e.Before {
+			changed = append(changed, e.Path)
+		}
+	}
+
+	if len(changed) > 0 {
+		return fmt.Errorf("%s: changed since planning: %s", dir, strings.Join(changed, ", "))
+	}
+
+	return nil
+}
+
+// Result is the outcome of applying the plan of a repository, Status is
+// done, skipped or failed.
+type Result struct {
+	Repo   string `json:"repo"`
+	Status string `json:"status"`
+	PR     string `json:"pr,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Err    error  `json:"-"`
+}
+
+// Plan works out the change of every repository: the target version after
+// the repository's own limits, the edited files and the branch, commit and
+// pull request. Remote repositories and worktrees are checked out for the
+// duration of the planning only.
+func (w Worker) Plan(ctx context.Context) (*Plan, error) {
+	if err := w.cfg.Commit.validate(); err != nil {
+		return nil, err
+	}
+	if err := w.cfg.PullRequest.validate(); err != nil {
+		return nil, err
+	}
+	for _, p := range w.cfg.Policies {
+		if p.kind == "" {
+			return nil, fmt.Errorf("policy %q isn't resolved, use ReleaseIndex.ResolvePolicies", p.Target)
+		}
+	}
+
+	repos, err := w.repos()
+	if err != nil {
+		return nil, err
+	}
+
+	w.progress.setTotal(len(repos))
+	plan := &Plan{Version: w.version, Config: w.cfg, Repos: make([]RepoPlan, len(repos))}
+	var wg sync.WaitGroup
+	wp := workerpool.New(w.cfg.Concurrency)
+	for i, name := range repos {
+		i, name := i, name
+		wg.Add(1)
+		wp.Submit(func() {
+			defer wg.Done()
+			plan.Repos[i] = w.planRepo(ctx, name)
+		})
+	}
+
+	wg.Wait()
+	wp.Stop()
+	return plan, ctx.Err()
+}
+
+func (w Worker) planRepo(ctx context.Context, name string) (rp RepoPlan) {
+	rp = RepoPlan{Name: name, To: w.version}
+	defer func() {
+		switch {
+		case rp.Error != "":
+			w.progress.finish(name, statusFailed)
+		case rp.Skip != "":
+			w.progress.finish(name, statusSkipped)
+		default:
+			w.progress.finish(name, statusDone)
+		}
+	}()
+	w.log = w.log.With("repo", name)
+	if url, ok := w.remotes[name]; ok {
+		rp.Remote = url
+	} else {
+		rp.Dir = filepath.Join(w.path, name)
+	}
+
+	if err := ctx.Err(); err != nil {
+		rp.Error = Redact(err.Error())
+		return rp
+	}
+
+	if w.campaign.completed(name) {
+		rp.Skip = "already completed"
+		return rp
+	}
+
+	ctx, closeLog, err := w.openLog(ctx, name)
+	if err != nil {
+		rp.Error = Redact(err.Error())
+		return rp
+	}
+	defer closeLog()
+
+	if rp.Remote == "" && w.cfg.Worktree {
+		if _, err := git(ctx, rp.Dir, "rev-parse", "--git-dir"); err != nil {
+			rp.Skip = "not a git repository"
+			return rp
+		}
+	}
+
+	w.enter(name, progressDiscover)
+	dir, cleanup, err := w.checkout(ctx, rp)
+	if err != nil {
+		rp.Error = Redact(err.Error())
+		return rp
+	}
+	defer cleanup()
+
+	if base, err := git(ctx, dir, "rev-parse", "HEAD"); err == nil && (rp.Remote != "" || w.cfg.Worktree) {
+		rp.Base = base
+	}
+
+	if err := w.planIn(ctx, dir, &rp); err != nil {
+		w.log.Error("planning failed", "stage", "plan", "err", err)
+		rp.Error = Redact(err.Error())
+		return rp
+	}
+
+	if rp.Skip != "" {
+		w.log.Debug("planned to skip", "stage", "plan", "reason", rp.Skip)
+		return rp
+	}
+
+	w.log.Info("planned", "stage", "plan", "from", rp.From, "to", rp.To, "files", len(rp.Edits))
+	return rp
+}
+
+func (w *Worker) planIn(ctx context.Context, dir string, rp *RepoPlan) error {
+	repoCfg, err := LoadRepoConfig(dir)
+	if err != nil {
+		return err
+	}
+
+	if repoCfg.Skip {
+		rp.Skip = "opted out in " + repoConfigFile
+		return nil
+	}
+
+	w.repoCfg = repoCfg
+
+	// the go directive before go.mod gets edited
+	current := ""
+	if read, err := ioutil.ReadFile(filepath.Join(dir, goMod)); err == nil {
+		rp.Module, current = moduleDirective(read), goDirective(read)
+	}
+
+	// a retried bump of a campaign finds the files already edited
+	state := w.campaign.state(rp.Name)
+	fresh := state.Stage == "" || state.Stage == stageDiscovered
+
+	if policy, ok := matchPolicy(w.cfg.Policies, rp.Name, rp.Module, time.Now()); ok {
+		target, complies, above := policy.evaluate(current)
+		rp.Policy, rp.To, w.version = policy.Target, target, target
+		if above {
+			rp.From, rp.Above = current, true
+			rp.Skip = fmt.Sprintf("go %s is above policy %q, which never downgrades", current, policy.Target)
+			return nil
+		}
+		if complies && fresh {
+			rp.From, rp.To, rp.Complies = current, current, true
+			rp.Skip = fmt.Sprintf("complies with policy %q", policy.Target)
+			return nil
+		}
+	}
+
+	if repoCfg.MaxVersion != "" {
+		// max_version caps the bump, it never takes a repository back
+		capped := current
+		if len(versionParts(repoCfg.MaxVersion)) < 3 {
+			capped = minorOf(current)
+		}
+		if current != "" && compareVersions(capped, repoCfg.MaxVersion) > 0 {
+			rp.From, rp.To = current, current
+			rp.Skip = fmt.Sprintf("go %s is already above max_version %s", current, repoCfg.MaxVersion)
+			return nil
+		}
+		if compareVersions(w.version, repoCfg.MaxVersion) > 0 {
+			w.version = repoCfg.MaxVersion
+		}
+	}
+
+	if err := w.checkDeps(ctx, dir); err != nil {
+		return err
+	}
+
+	if err := w.checkDowngrade(dir); err != nil {
+		return err
+	}
+
+	if err := filepath.Walk(dir, w.visit); err != nil {
+		return err
+	}
+
+	// the root module decides the old version, nested ones only stand in
+	// for a missing root go.mod
+	w.currentGo = current
+	for _, f := range w.files {
+		if w.currentGo != "" || f.name != goMod {
+			continue
+		}
+		if read, err := ioutil.ReadFile(f.path); err == nil {
+			w.currentGo = goDirective(read)
+		}
+	}
+
+	// no go.mod with version exit
+	if w.currentGo == "" {
+		rp.Skip = "no go.mod"
+		return nil
+	}
+
+	rp.From, rp.To = w.currentGo, w.version
+	if state.From != "" {
+		rp.From = state.From
+	}
+
+	editors := w.editors()
+	for _, f := range w.files {
+		rel, err := filepath.Rel(dir, f.path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		read, edited, summary, err := w.editFile(editors, f.path, rel)
+		if err != nil {
+			return err
+		}
+		if edited != nil {
+			rp.Edits = append(rp.Edits, FileEdit{
+				Path:    rel,
+				Before:  contentHash(read),
+				After:   contentHash(edited),
+				Summary: summary,
+				Content: string(edited),
+			})
+		}
+	}
+
+	if len(rp.Edits) == 0 && w.cfg.Deps.Mode == "" && fresh {
+		rp.Complies = true
+		rp.Skip = "already on go " + rp.To
+		return nil
+	}
+
+	data := templateData{Repo: rp.Name, From: rp.From, To: rp.To}
+	if rp.Branch, err = render(w.cfg.Templates.Branch, data); err != nil {
+		return err
+	}
+	if rp.Commit, err = render(w.cfg.Templates.Commit, data); err != nil {
+		return err
+	}
+	if rp.Title, err = render(w.cfg.Templates.Title, data); err != nil {
+		return err
+	}
+
+	rp.Steps = w.applySteps()
+	rp.Inputs = map[string]string{}
+	for _, path := range planInputs {
+		rp.Inputs[path] = fileHash(filepath.Join(dir, filepath.FromSlash(path)))
+	}
+
+	return nil
+}
+
+// applySteps lists what apply runs besides writing the edits, in order.
+func (w *Worker) applySteps() []string {
+	var steps []string
+	hooks := func(stage string) {
+		for _, command := range append(append([]string(nil), w.cfg.Hooks.commands(stage)...), w.repoCfg.Hooks.commands(stage)...) {
+			steps = append(steps, stage+" hook: "+command)
+		}
+	}
+
+	hooks(hookPreEdit)
+	if commands, err := w.depsCommands(); err == nil {
+		for _, args := range commands {
+			steps = append(steps, "go "+strings.Join(args, " "))
+		}
+	}
+	steps = append(steps, "go mod vendor")
+	hooks(hookPostEdit)
+	hooks(hookPreCommit)
+	hooks(hookPostPR)
+
+	return steps
+}
+
+// editFile runs the matching editors over a file and returns its content
+// and the new one, nil when the file is left unchanged, with a summary of
+// the editors that changed it.
+func (w *Worker) editFile(editors []Editor, path, rel string) (read, edited []byte, summary string, err error) {
+	matched := false
+	var summaries []string
+	for _, e := range editors {
+		if !e.Match(rel) {
+			continue
+		}
+
+		if !matched {
+			if read, err = ioutil.ReadFile(path); err != nil {
+				return nil, nil, "", err
+			}
+			edited, matched = read, true
+		}
+
+		before := edited
+		if edited, err = e.Edit(rel, edited, w.currentGo, w.version); err != nil {
+			return nil, nil, "", fmt.Errorf("%s: %v", rel, err)
+		}
+		if bytes.Equal(before, edited) {
+			continue
+		}
+
+		line := "edited"
+		if s, ok := e.(summarizer); ok {
+			line = s.Summary(w.currentGo, w.version)
+		}
+		summaries = append(summaries, line)
+	}
+
+	if !matched || bytes.Equal(read, edited) {
+		return read, nil, "", nil
+	}
+
+	return read, edited, strings.Join(summaries, "; "), nil
+}
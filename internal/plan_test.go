@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckEdits(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                    "module x\n\ngo 1.21\n",
+		".github/workflows/ci.yaml": "go: 1.21\n",
+	})
+
+	tests := []struct {
+		name    string
+		edits   []FileEdit
+		changed string
+	}{
+		{
+			name: "unchanged",
+			edits: []FileEdit{
+				{Path: "go.mod", Before: contentHash([]byte("module x\n\ngo 1.21\n"))},
+				{Path: ".github/workflows/ci.yaml", Before: contentHash([]byte("go: 1.21\n"))},
+			},
+		},
+		{
+			name: "changed",
+			edits: []FileEdit{
+				{Path: "go.mod", Before: contentHash([]byte("module x\n\ngo 1.20\n"))},
+				{Path: ".github/workflows/ci.yaml", Before: contentHash([]byte("go: 1.21\n"))},
+			},
+			changed: "go.mod",
+		},
+		{
+			name:    "missing",
+			edits:   []FileEdit{{Path: "Dockerfile", Before: contentHash([]byte("FROM golang:1.21\n"))}},
+			changed: "Dockerfile",
+		},
+		{
+			name: "no edits",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkEdits(dir, tt.edits)
+			if tt.changed == "" {
+				if err != nil {
+					t.Fatalf("checkEdits() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasSuffix(err.Error(), "changed since planning: "+tt.changed) {
+				t.Errorf("checkEdits() = %v, want %s reported as changed", err, tt.changed)
+			}
+		})
+	}
+}
+
+func TestCheckPlanned(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module x\n\ngo 1.21\n",
+		"go.sum": "example.com/y v1.0.0 h1:abc=\n",
+	})
+	mod := contentHash([]byte("module x\n\ngo 1.21\n"))
+	sum := contentHash([]byte("example.com/y v1.0.0 h1:abc=\n"))
+
+	tests := []struct {
+		name   string
+		inputs map[string]string
+		stale  bool
+	}{
+		{name: "unchanged", inputs: map[string]string{goMod: mod, "go.sum": sum, repoConfigFile: ""}},
+		{name: "input changed", inputs: map[string]string{goMod: mod, "go.sum": contentHash([]byte("old"))}, stale: true},
+		{name: "input added", inputs: map[string]string{goMod: mod, "go.sum": ""}, stale: true},
+		{name: "input removed", inputs: map[string]string{repoConfigFile: contentHash([]byte("skip: true\n"))}, stale: true},
+		{name: "planned without inputs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := RepoPlan{
+				Edits:  []FileEdit{{Path: goMod, Before: mod}},
+				Inputs: tt.inputs,
+			}
+			if err := checkPlanned(dir, rp); (err != nil) != tt.stale {
+				t.Errorf("checkPlanned() = %v, want stale %v", err, tt.stale)
+			}
+		})
+	}
+}
+
+func TestPlanCheck(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a/go.mod": "module a\n\ngo 1.21\n",
+		"b/go.mod": "module b\n\ngo 1.22\n",
+	})
+	planned := func(name, content string) RepoPlan {
+		return RepoPlan{
+			Name:  name,
+			Dir:   filepath.Join(dir, name),
+			Edits: []FileEdit{{Path: goMod, Before: contentHash([]byte(content))}},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		repos []RepoPlan
+		stale string
+	}{
+		{
+			name:  "fresh",
+			repos: []RepoPlan{planned("a", "module a\n\ngo 1.21\n"), planned("b", "module b\n\ngo 1.22\n")},
+		},
+		{
+			name:  "stale",
+			repos: []RepoPlan{planned("a", "module a\n\ngo 1.21\n"), planned("b", "module b\n\ngo 1.21\n")},
+			stale: filepath.Join(dir, "b"),
+		},
+		{
+			name: "skipped and failed repositories are ignored",
+			repos: []RepoPlan{
+				func() RepoPlan { rp := planned("a", "old"); rp.Skip = "no go.mod"; return rp }(),
+				func() RepoPlan { rp := planned("b", "old"); rp.Error = "boom"; return rp }(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plan{Repos: tt.repos}
+			err := p.Check(context.Background())
+			if tt.stale == "" {
+				if err != nil {
+					t.Fatalf("Check() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.stale) {
+				t.Errorf("Check() = %v, want %s reported as stale", err, tt.stale)
+			}
+		})
+	}
+}
+
+func TestPlanCheckRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote")
+	for _, args := range [][]string{
+		{"init", "-q", remote},
+		{"-C", remote, "-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+	head, err := git(context.Background(), remote, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		base  string
+		stale bool
+	}{
+		{name: "at the planned commit", base: head},
+		{name: "moved on", base: strings.Repeat("0", len(head)), stale: true},
+		{name: "planned without a base"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plan{Repos: []RepoPlan{{Name: "remote", Remote: remote, Base: tt.base}}}
+			if err := p.Check(context.Background()); (err != nil) != tt.stale {
+				t.Errorf("Check() = %v, want stale %v", err, tt.stale)
+			}
+		})
+	}
+}
+
+func TestRepoPlanViolates(t *testing.T) {
+	tests := []struct {
+		name     string
+		rp       RepoPlan
+		violates bool
+	}{
+		{name: "behind", rp: RepoPlan{From: "1.21", To: "1.22"}, violates: true},
+		{name: "complies", rp: RepoPlan{From: "1.22", To: "1.22", Complies: true, Skip: `complies with policy "latest"`}},
+		{name: "above a pin", rp: RepoPlan{From: "1.23", To: "1.23", Above: true, Skip: `go 1.23 is above policy "pin 1.22", which never downgrades`}, violates: true},
+		{name: "above max_version", rp: RepoPlan{From: "1.23", To: "1.23", Skip: "go 1.23 is already above max_version 1.22"}},
+		{name: "failed to plan", rp: RepoPlan{Error: "go.mod: no go directive"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rp.Violates(); got != tt.violates {
+				t.Errorf("Violates() = %v, want %v", got, tt.violates)
+			}
+		})
+	}
+}
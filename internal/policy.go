@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	policyExact = "exact"
+	policyFloor = "floor"
+	policyPin   = "pin"
+
+	pinDateLayout = "2006-01-02"
+)
+
+// Policy decides the target of the repositories whose name matches the
+// Match glob or whose module path matches the Module glob, a policy setting
+// neither matches every repository. The first matching policy wins, the
+// others fall back to the global version. Target is one of
+//
+//	latest               the latest stable minor
+//	latest-1             at least the minor before it, the oldest supported
+//	>=1.21               at least go 1.21
+//	pin 1.20 until 2026-12-01
+//	                     go 1.20, until the pin expires on that day
+//
+// or any version --version takes. Floors bump repositories below them to
+// the newest patch of the floor and leave the others alone. Pins and exact
+// targets bump to their version, a repository above it is left alone and
+// doesn't comply.
+type Policy struct {
+	Match  string `yaml:"match"`
+	Module string `yaml:"module"`
+	Target string `yaml:"target"`
+
+	kind    string
+	version string
+	to      string
+	until   time.Time
+}
+
+// ResolvePolicies checks the policies and resolves their targets against
+// the index, which is only loaded when a policy needs it.
+func (r ReleaseIndex) ResolvePolicies(policies []Policy) ([]Policy, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	var releases []Release
+	load := func() ([]Release, error) {
+		if releases != nil {
+			return releases, nil
+		}
+		var err error
+		releases, err = r.Releases()
+		return releases, err
+	}
+
+	resolved := make([]Policy, len(policies))
+	for i, p := range policies {
+		// the policies of a resumed campaign keep the targets it started
+		// with, a later release must not move them
+		if p.kind != "" {
+			resolved[i] = p
+			continue
+		}
+		if err := p.resolve(load); err != nil {
+			return nil, fmt.Errorf("policy %q: %v", p.Target, err)
+		}
+		resolved[i] = p
+	}
+
+	return resolved, nil
+}
+
+func (p *Policy) resolve(load func() ([]Release, error)) error {
+	if p.Match != "" {
+		if _, err := path.Match(p.Match, ""); err != nil {
+			return err
+		}
+	}
+	if p.Module != "" {
+		if _, err := path.Match(p.Module, ""); err != nil {
+			return err
+		}
+	}
+
+	target := strings.TrimSpace(p.Target)
+	switch {
+	case target == "":
+		return fmt.Errorf("no target")
+	case strings.HasPrefix(target, "pin "):
+		fields := strings.Fields(target)
+		switch {
+		case len(fields) == 2:
+		case len(fields) == 4 && fields[2] == "until":
+			until, err := time.ParseInLocation(pinDateLayout, fields[3], time.Local)
+			if err != nil {
+				return fmt.Errorf("pin date %q isn't YYYY-MM-DD", fields[3])
+			}
+			p.until = until
+		default:
+			return fmt.Errorf("use `pin 1.20` or `pin 1.20 until 2026-12-01`")
+		}
+		p.kind, p.version = policyPin, fields[1]
+	case strings.HasPrefix(target, ">="):
+		p.kind, p.version = policyFloor, strings.TrimSpace(strings.TrimPrefix(target, ">="))
+	case target == "latest" || strings.HasPrefix(target, "latest-"):
+		back := 0
+		if target != "latest" {
+			n, err := strconv.Atoi(strings.TrimPrefix(target, "latest-"))
+			if err != nil || n < 0 {
+				return fmt.Errorf("use latest-N with N a number of minors")
+			}
+			back = n
+		}
+		releases, err := load()
+		if err != nil {
+			return err
+		}
+		minors := stableMinors(releases)
+		if back >= len(minors) {
+			return fmt.Errorf("the index has only %d stable minors", len(minors))
+		}
+		p.kind, p.version = policyFloor, minors[back]
+	default:
+		p.kind = policyExact
+		if !symbolicVersion(target) {
+			p.version = target
+			break
+		}
+		releases, err := load()
+		if err != nil {
+			return err
+		}
+		if p.version, err = resolve(releases, target); err != nil {
+			return err
+		}
+	}
+
+	if len(versionParts(p.version)) < 2 {
+		return fmt.Errorf("%q isn't a go version", p.version)
+	}
+
+	p.to = p.version
+	if p.kind == policyFloor && len(versionParts(p.version)) == 2 {
+		releases, err := load()
+		if err != nil {
+			return err
+		}
+		if p.to, err = resolve(releases, p.version+".x"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// policyJSON is a Policy with its resolved target, the way campaigns and
+// plans save it.
+type policyJSON struct {
+	Match   string
+	Module  string
+	Target  string
+	Kind    string `json:",omitempty"`
+	Version string `json:",omitempty"`
+	To      string `json:",omitempty"`
+	Until   string `json:",omitempty"`
+}
+
+func (p Policy) MarshalJSON() ([]byte, error) {
+	j := policyJSON{Match: p.Match, Module: p.Module, Target: p.Target, Kind: p.kind, Version: p.version, To: p.to}
+	if !p.until.IsZero() {
+		j.Until = p.until.Format(pinDateLayout)
+	}
+
+	return json.Marshal(j)
+}
+
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var j policyJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*p = Policy{Match: j.Match, Module: j.Module, Target: j.Target, kind: j.Kind, version: j.Version, to: j.To}
+	if j.Until != "" {
+		until, err := time.ParseInLocation(pinDateLayout, j.Until, time.Local)
+		if err != nil {
+			return fmt.Errorf("policy %q: pin date %q isn't YYYY-MM-DD", j.Target, j.Until)
+		}
+		p.until = until
+	}
+
+	return nil
+}
+
+// stableMinors are the minors with a stable release, newest first.
+func stableMinors(releases []Release) []string {
+	var minors []string
+	seen := map[string]bool{}
+	for _, rel := range releases {
+		minor := minorOf(rel.Version)
+		if rel.Stable && !seen[minor] {
+			seen[minor] = true
+			minors = append(minors, minor)
+		}
+	}
+
+	sort.Slice(minors, func(i, j int) bool {
+		return compareVersions(minors[i], minors[j]) > 0
+	})
+
+	return minors
+}
+
+// matchPolicy returns the first policy matching the repository or its
+// module, expired pins are passed over.
+func matchPolicy(policies []Policy, repo, module string, now time.Time) (Policy, bool) {
+	for _, p := range policies {
+		if p.kind == policyPin && !p.until.IsZero() && !now.Before(p.until) {
+			continue
+		}
+		if p.matches(repo, module) {
+			return p, true
+		}
+	}
+
+	return Policy{}, false
+}
+
+func (p Policy) matches(repo, module string) bool {
+	if p.Match == "" && p.Module == "" {
+		return true
+	}
+
+	if p.Match != "" {
+		if ok, _ := path.Match(p.Match, repo); ok {
+			return true
+		}
+	}
+
+	if p.Module != "" && module != "" {
+		if ok, _ := path.Match(p.Module, module); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// evaluate returns the target of a repository on go current, whether it
+// complies with the policy already and whether it is above the version of
+// a pin or exact target. Such a repository violates the policy but is never
+// taken back, its target stays current.
+func (p Policy) evaluate(current string) (target string, complies, above bool) {
+	if current == "" {
+		return p.to, false, false
+	}
+
+	switch cmp := atVersion(current, p.version); {
+	case p.kind == policyFloor && cmp >= 0, cmp == 0:
+		return current, true, false
+	case p.kind != policyFloor && cmp > 0:
+		return current, false, true
+	}
+
+	return p.to, false, false
+}
+
+// atVersion compares current with a policy version, only by minor when the
+// policy version has no patch.
+func atVersion(current, version string) int {
+	if len(versionParts(version)) == 2 {
+		return compareVersions(minorOf(current), version)
+	}
+
+	return compareVersions(current, version)
+}
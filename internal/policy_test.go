@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPolicyResolve(t *testing.T) {
+	tests := []struct {
+		target  string
+		kind    string
+		version string
+		to      string
+		until   string
+		wantErr bool
+	}{
+		{target: "latest", kind: policyFloor, version: "1.23", to: "1.23.2"},
+		{target: "latest-1", kind: policyFloor, version: "1.22", to: "1.22.8"},
+		{target: "latest-2", kind: policyFloor, version: "1.21", to: "1.21.13"},
+		{target: "latest-3", wantErr: true},
+		{target: "latest-x", wantErr: true},
+		{target: ">=1.22", kind: policyFloor, version: "1.22", to: "1.22.8"},
+		{target: ">= 1.22.3", kind: policyFloor, version: "1.22.3", to: "1.22.3"},
+		{target: ">=1.20", wantErr: true},
+		{target: "pin 1.20", kind: policyPin, version: "1.20", to: "1.20"},
+		{target: "pin 1.20 until 2026-12-01", kind: policyPin, version: "1.20", to: "1.20", until: "2026-12-01"},
+		{target: "pin 1.20 until tomorrow", wantErr: true},
+		{target: "pin 1.20 for ever", wantErr: true},
+		{target: "stable", kind: policyExact, version: "1.23.2", to: "1.23.2"},
+		{target: "1.22.x", kind: policyExact, version: "1.22.8", to: "1.22.8"},
+		{target: "1.22.3", kind: policyExact, version: "1.22.3", to: "1.22.3"},
+		{target: "go", wantErr: true},
+		{target: " ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			p := Policy{Target: tt.target}
+			err := p.resolve(func() ([]Release, error) { return testReleases, nil })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolve(%q) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.kind != tt.kind || p.version != tt.version || p.to != tt.to {
+				t.Errorf("resolve(%q) = %s %s to %s, want %s %s to %s", tt.target, p.kind, p.version, p.to, tt.kind, tt.version, tt.to)
+			}
+			if until := p.until.Format(pinDateLayout); tt.until != "" && until != tt.until {
+				t.Errorf("resolve(%q) until = %s, want %s", tt.target, until, tt.until)
+			}
+		})
+	}
+}
+
+func TestPolicyResolvedKeptByCampaign(t *testing.T) {
+	var policies []Policy
+	for _, target := range []string{"latest-1", "pin 1.20 until 2026-12-01", "stable"} {
+		p := Policy{Match: "a*", Target: target}
+		if err := p.resolve(func() ([]Release, error) { return testReleases, nil }); err != nil {
+			t.Fatal(err)
+		}
+		policies = append(policies, p)
+	}
+
+	dir := t.TempDir()
+	if _, err := NewCampaign(dir, "c", "", nil, Config{Policies: policies}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := OpenCampaign(dir, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// resuming doesn't need the index, the targets are those the campaign
+	// started with
+	offline := NewReleaseIndex("", filepath.Join(t.TempDir(), "index.json"), true)
+	resolved, err := offline.ResolvePolicies(c.Config.Policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resolved, policies) {
+		t.Errorf("resumed policies = %+v, want %+v", resolved, policies)
+	}
+}
+
+func TestPolicyResolveLoadsIndexOnlyWhenNeeded(t *testing.T) {
+	failing := func() ([]Release, error) { return nil, errors.New("offline") }
+
+	for _, target := range []string{"pin 1.20", ">=1.22.3", "1.22.3"} {
+		p := Policy{Target: target}
+		if err := p.resolve(failing); err != nil {
+			t.Errorf("resolve(%q) = %v, want no index needed", target, err)
+		}
+	}
+
+	for _, target := range []string{"latest", ">=1.22", "stable"} {
+		p := Policy{Target: target}
+		if err := p.resolve(failing); err == nil {
+			t.Errorf("resolve(%q) succeeded without the index", target)
+		}
+	}
+}
+
+func TestPolicyResolveInvalidGlob(t *testing.T) {
+	for _, p := range []Policy{{Match: "[", Target: "latest"}, {Module: "[", Target: "latest"}} {
+		if err := p.resolve(func() ([]Release, error) { return testReleases, nil }); err == nil {
+			t.Errorf("resolve(%+v) succeeded, want a bad pattern error", p)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	floor := Policy{kind: policyFloor, version: "1.22", to: "1.22.8"}
+	patchFloor := Policy{kind: policyFloor, version: "1.22.3", to: "1.22.3"}
+	pin := Policy{kind: policyPin, version: "1.20", to: "1.20"}
+	exact := Policy{kind: policyExact, version: "1.22.3", to: "1.22.3"}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		current  string
+		target   string
+		complies bool
+		above    bool
+	}{
+		{name: "below floor", policy: floor, current: "1.21", target: "1.22.8"},
+		{name: "at floor minor", policy: floor, current: "1.22.0", target: "1.22.0", complies: true},
+		{name: "above floor", policy: floor, current: "1.23", target: "1.23", complies: true},
+		{name: "below patch floor", policy: patchFloor, current: "1.22.1", target: "1.22.3"},
+		{name: "no go version", policy: floor, target: "1.22.8"},
+		{name: "below pin", policy: pin, current: "1.19", target: "1.20"},
+		{name: "at pin", policy: pin, current: "1.20.5", target: "1.20.5", complies: true},
+		{name: "above pin", policy: pin, current: "1.21", target: "1.21", above: true},
+		{name: "below exact", policy: exact, current: "1.22.1", target: "1.22.3"},
+		{name: "at exact", policy: exact, current: "1.22.3", target: "1.22.3", complies: true},
+		{name: "above exact", policy: exact, current: "1.22.5", target: "1.22.5", above: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, complies, above := tt.policy.evaluate(tt.current)
+			if target != tt.target || complies != tt.complies || above != tt.above {
+				t.Errorf("evaluate(%q) = %q, %v, %v, want %q, %v, %v", tt.current, target, complies, above, tt.target, tt.complies, tt.above)
+			}
+		})
+	}
+}
+
+func TestMatchPolicy(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+	policies := []Policy{
+		{Match: "legacy-*", kind: policyPin, version: "1.20", until: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)},
+		{Match: "frozen", kind: policyPin, version: "1.20", until: time.Date(2027, 1, 1, 0, 0, 0, 0, time.Local)},
+		{Module: "example.com/lib/*", kind: policyFloor, version: "1.22"},
+		{kind: policyFloor, version: "1.23"},
+	}
+
+	tests := []struct {
+		repo    string
+		module  string
+		version string
+	}{
+		{repo: "legacy-api", module: "example.com/legacy-api", version: "1.23"},
+		{repo: "frozen", version: "1.20"},
+		{repo: "x", module: "example.com/lib/x", version: "1.22"},
+		{repo: "y", version: "1.23"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			p, ok := matchPolicy(policies, tt.repo, tt.module, now)
+			if !ok || p.version != tt.version {
+				t.Errorf("matchPolicy(%q, %q) = %+v, %v, want version %s", tt.repo, tt.module, p, ok, tt.version)
+			}
+		})
+	}
+}
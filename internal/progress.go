@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressDiscover = "discover"
+	progressEdit     = "edit"
+	progressVendor   = "vendor"
+	progressVerify   = "verify"
+	progressCommit   = "commit"
+	progressPush     = "push"
+	progressPR       = "pr"
+
+	progressRefresh  = 250 * time.Millisecond
+	progressMaxLines = 20
+)
+
+// Progress redraws a live view of the repositories being bumped on a
+// terminal: the stage every active repository is in, the counters and an
+// ETA. It is also an io.Writer for the logs so they scroll above the view
+// instead of tearing it. Every method is a no-op on a nil Progress.
+type Progress struct {
+	mu      sync.Mutex
+	out     io.Writer
+	started time.Time
+	total   int
+	counts  map[string]int
+	active  map[string]*repoProgress
+	lines   int
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+type repoProgress struct {
+	stage   string
+	started time.Time
+}
+
+// NewProgress starts redrawing the view on out until Stop.
+func NewProgress(out io.Writer) *Progress {
+	p := &Progress{
+		out:     out,
+		started: time.Now(),
+		counts:  map[string]int{},
+		active:  map[string]*repoProgress{},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go p.refresh()
+	return p
+}
+
+func (p *Progress) refresh() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(progressRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.mu.Lock()
+			p.redraw()
+			p.mu.Unlock()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Stop draws the view a last time and leaves it on the terminal.
+func (p *Progress) Stop() {
+	if p == nil {
+		return
+	}
+
+	close(p.stop)
+	<-p.stopped
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.redraw()
+	p.lines = 0
+}
+
+// Write prints log lines above the view.
+func (p *Progress) Write(b []byte) (int, error) {
+	if p == nil {
+		return len(b), nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	n, err := p.out.Write(b)
+	p.draw()
+	return n, err
+}
+
+// setTotal starts counting a new pass over n repositories, planning and
+// applying each get their own.
+func (p *Progress) setTotal(n int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total, p.started = n, time.Now()
+	p.counts = map[string]int{}
+}
+
+func (p *Progress) stage(repo, stage string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r, ok := p.active[repo]; ok {
+		r.stage = stage
+		return
+	}
+	p.active[repo] = &repoProgress{stage: stage, started: time.Now()}
+}
+
+func (p *Progress) finish(repo, status string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.active, repo)
+	p.counts[status]++
+}
+
+func (p *Progress) redraw() {
+	p.clear()
+	p.draw()
+}
+
+// clear moves the cursor back to the start of the view and erases it.
+func (p *Progress) clear() {
+	if p.lines > 0 {
+		fmt.Fprintf(p.out, "\x1b[%dA\x1b[J", p.lines)
+		p.lines = 0
+	}
+}
+
+func (p *Progress) draw() {
+	now := time.Now()
+	elapsed := now.Sub(p.started)
+	finished := p.counts[statusDone] + p.counts[statusSkipped] + p.counts[statusFailed]
+
+	eta := "-"
+	if finished > 0 && p.total > finished {
+		eta = roundDuration(elapsed / time.Duration(finished) * time.Duration(p.total-finished))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d/%d  done %d  skipped %d  failed %d  elapsed %s  eta %s\n",
+		finished, p.total, p.counts[statusDone], p.counts[statusSkipped], p.counts[statusFailed],
+		roundDuration(elapsed), eta)
+
+	repos := make([]string, 0, len(p.active))
+	for repo := range p.active {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return p.active[repos[i]].started.Before(p.active[repos[j]].started)
+	})
+
+	lines := 1
+	for i, repo := range repos {
+		if i == progressMaxLines {
+			fmt.Fprintf(&b, "  ... and %d more\n", len(repos)-i)
+			lines++
+			break
+		}
+
+		r := p.active[repo]
+		// a wrapped line would throw off clear
+		name := repo
+		if len(name) > 40 {
+			name = "..." + name[len(name)-37:]
+		}
+		fmt.Fprintf(&b, "  %-40s %-8s %s\n", name, r.stage, roundDuration(now.Sub(r.started)))
+		lines++
+	}
+
+	io.WriteString(p.out, b.String())
+	p.lines = lines
+}
+
+func roundDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
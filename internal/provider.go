@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	prOpen   = "open"
+	prMerged = "merged"
+	prClosed = "closed"
+
+	mergeMerge  = "merge"
+	mergeSquash = "squash"
+	mergeRebase = "rebase"
+
+	checksSuccess = "success"
+	checksFailure = "failure"
+	checksPending = "pending"
+	checksNone    = "none"
+
+	reviewApproved         = "approved"
+	reviewChangesRequested = "changes_requested"
+	reviewPending          = "pending"
+)
+
+// PullRequest is the state of a bump pull request as reported by the
+// provider. Error is set when its state couldn't be queried.
+type PullRequest struct {
+	Repo      string `json:"repo"`
+	URL       string `json:"url"`
+	State     string `json:"state"`
+	Checks    string `json:"checks"`
+	Review    string `json:"review"`
+	Conflicts bool   `json:"conflicts"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Blocker is why the pull request can't be merged yet, empty when it is
+// open, its checks passed, it is approved and has no conflicts.
+func (pr PullRequest) Blocker() string {
+	switch {
+	case pr.Error != "":
+		return "error: " + pr.Error
+	case pr.State != prOpen:
+		return pr.State
+	case pr.Conflicts:
+		return "conflicts"
+	case pr.Checks != checksSuccess:
+		return "checks " + pr.Checks
+	case pr.Review != reviewApproved:
+		return "review " + pr.Review
+	}
+
+	return ""
+}
+
+func validateMergeMethod(method string) error {
+	switch method {
+	case mergeMerge, mergeSquash, mergeRebase:
+		return nil
+	}
+
+	return fmt.Errorf("unknown merge method %q, use merge, squash or rebase", method)
+}
+
+// NewPullRequest is the pull request opened for a pushed bump branch.
+// Reviewers and Assignees are user logins, TeamReviewers team slugs and
+// Milestone a milestone title or number. A non empty AutoMerge enables
+// auto-merge with that method. Fork is the `owner/name` of the fork Head
+// was pushed to in fork mode.
+type NewPullRequest struct {
+	Title         string
+	Body          string
+	Head          string
+	Fork          string
+	Draft         bool
+	Labels        []string
+	Reviewers     []string
+	TeamReviewers []string
+	Assignees     []string
+	Milestone     string
+	AutoMerge     string
+}
+
+// Provider is the code hosting service the pull requests are opened on.
+// OpenPullRequest targets the default branch of the origin remote of the
+// checkout in dir and returns the pull request's web URL, together with an
+// error when some of its options couldn't be set.
+type Provider interface {
+	OpenPullRequest(ctx context.Context, dir string, pr NewPullRequest) (string, error)
+	PullRequest(ctx context.Context, url string) (PullRequest, error)
+	Repositories(ctx context.Context, org string) ([]string, error)
+}
+
+// Forker is implemented by a Provider supporting fork mode. Fork creates
+// the fork of the origin of dir under the user or organization owner, or
+// returns the existing one.
+type Forker interface {
+	Fork(ctx context.Context, dir, owner string) (Fork, error)
+}
+
+// Merger is implemented by a Provider which merges pull requests with the
+// merge, squash or rebase method.
+type Merger interface {
+	Merge(ctx context.Context, url, method string) error
+}
+
+func newProvider(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "hub":
+		return newGitHub(hubAPI{}, cfg.Retry), nil
+	case "github":
+		return newGitHub(newRestAPI("github", cfg.Hosts), cfg.Retry), nil
+	case "gitlab":
+		return newGitLab(newRestAPI("gitlab", cfg.Hosts), cfg.Retry), nil
+	}
+
+	return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+}
+
+// prRef is a pull request parsed from its web URL,
+// https://github.com/owner/repo/pull/1.
+type prRef struct {
+	host   string
+	owner  string
+	repo   string
+	number int
+}
+
+func parsePRURL(raw string) (prRef, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return prRef{}, err
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 4 || (parts[2] != "pull" && parts[2] != "pulls") {
+		return prRef{}, fmt.Errorf("not a pull request url: %s", raw)
+	}
+
+	number, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return prRef{}, fmt.Errorf("not a pull request url: %s", raw)
+	}
+
+	return prRef{host: u.Host, owner: parts[0], repo: parts[1], number: number}, nil
+}
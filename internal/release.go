@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultIndexURL = "https://go.dev/dl/?mode=json&include=all"
+	indexCacheFile  = "releases.json"
+)
+
+// Release is a single entry of the go.dev/dl/?mode=json index.
+type Release struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+// ReleaseIndex resolves symbolic targets such as `stable` against the go
+// release index. The index is read from an http(s) URL or a local file and
+// the last good copy is kept in the cache file for offline use.
+type ReleaseIndex struct {
+	url     string
+	cache   string
+	offline bool
+}
+
+func NewReleaseIndex(url, cache string, offline bool) ReleaseIndex {
+	if url == "" {
+		url = DefaultIndexURL
+	}
+
+	if cache == "" {
+		cache = defaultIndexCache()
+	}
+
+	return ReleaseIndex{
+		url:     url,
+		cache:   cache,
+		offline: offline,
+	}
+}
+
+func defaultIndexCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, configDir, indexCacheFile)
+}
+
+// Resolve turns `latest`, `stable`, `oldstable` or `1.22.x` into a concrete
+// version. Anything else is returned untouched.
+func (r ReleaseIndex) Resolve(target string) (string, error) {
+	if !symbolicVersion(target) {
+		return target, nil
+	}
+
+	releases, err := r.Releases()
+	if err != nil {
+		return "", err
+	}
+
+	return resolve(releases, target)
+}
+
+func symbolicVersion(target string) bool {
+	switch target {
+	case "latest", "stable", "oldstable":
+		return true
+	}
+
+	return strings.HasSuffix(target, ".x")
+}
+
+func resolve(releases []Release, target string) (string, error) {
+	var release Release
+	var ok bool
+
+	switch {
+	case target == "latest":
+		release, ok = newest(releases, func(Release) bool { return true })
+	case target == "stable":
+		release, ok = newest(releases, func(rel Release) bool { return rel.Stable })
+	case target == "oldstable":
+		var stable Release
+		if stable, ok = newest(releases, func(rel Release) bool { return rel.Stable }); ok {
+			minor := minorOf(stable.Version)
+			release, ok = newest(releases, func(rel Release) bool {
+				return rel.Stable && compareVersions(minorOf(rel.Version), minor) < 0
+			})
+		}
+	case strings.HasSuffix(target, ".x"):
+		minor := strings.TrimSuffix(target, ".x")
+		release, ok = newest(releases, func(rel Release) bool {
+			return rel.Stable && minorOf(rel.Version) == minorOf(minor)
+		})
+	default:
+		return target, nil
+	}
+
+	if !ok {
+		return "", fmt.Errorf("no go release matches %q", target)
+	}
+
+	return strings.TrimPrefix(release.Version, "go"), nil
+}
+
+// Releases loads the index, falling back to the cache file when the index
+// can't be reached or when running offline.
+func (r ReleaseIndex) Releases() ([]Release, error) {
+	if r.offline {
+		return readReleases(r.cache)
+	}
+
+	read, err := r.fetch()
+	if err != nil {
+		releases, cacheErr := readReleases(r.cache)
+		if cacheErr != nil {
+			return nil, fmt.Errorf("release index %s: %v", r.url, err)
+		}
+		return releases, nil
+	}
+
+	var releases []Release
+	if err := json.Unmarshal(read, &releases); err != nil {
+		return nil, fmt.Errorf("release index %s: %v", r.url, err)
+	}
+
+	if r.cache != "" {
+		if err := os.MkdirAll(filepath.Dir(r.cache), 0755); err == nil {
+			_ = ioutil.WriteFile(r.cache, read, 0644)
+		}
+	}
+
+	return releases, nil
+}
+
+func (r ReleaseIndex) fetch() ([]byte, error) {
+	if !strings.HasPrefix(r.url, "http://") && !strings.HasPrefix(r.url, "https://") {
+		return ioutil.ReadFile(strings.TrimPrefix(r.url, "file://"))
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(r.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func readReleases(path string) ([]Release, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no release index cache configured")
+	}
+
+	read, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []Release
+	if err := json.Unmarshal(read, &releases); err != nil {
+		return nil, fmt.Errorf("release index cache %s: %v", path, err)
+	}
+
+	return releases, nil
+}
+
+func newest(releases []Release, keep func(Release) bool) (Release, bool) {
+	var best Release
+	found := false
+	for _, rel := range releases {
+		if !keep(rel) {
+			continue
+		}
+		if !found || compareVersions(rel.Version, best.Version) > 0 ||
+			(compareVersions(rel.Version, best.Version) == 0 && rel.Stable && !best.Stable) {
+			best = rel
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// minorOf reduces `go1.22.3` or `1.22rc1` to `1.22`.
+func minorOf(v string) string {
+	parts := versionParts(v)
+	if len(parts) < 2 {
+		return strings.TrimPrefix(v, "go")
+	}
+
+	return fmt.Sprintf("%d.%d", parts[0], parts[1])
+}
@@ -0,0 +1,105 @@
+package internal
+
+import "testing"
+
+var testReleases = []Release{
+	{Version: "go1.24rc1", Stable: false},
+	{Version: "go1.23.2", Stable: true},
+	{Version: "go1.23.1", Stable: true},
+	{Version: "go1.22.8", Stable: true},
+	{Version: "go1.22.0", Stable: true},
+	{Version: "go1.21.13", Stable: true},
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{target: "latest", want: "1.24rc1"},
+		{target: "stable", want: "1.23.2"},
+		{target: "oldstable", want: "1.22.8"},
+		{target: "1.22.x", want: "1.22.8"},
+		{target: "1.21.x", want: "1.21.13"},
+		{target: "1.24.x", wantErr: true},
+		{target: "1.20.x", wantErr: true},
+		{target: "1.22.3", want: "1.22.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := resolve(testReleases, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolve(%q) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolve(%q) = %q, want %q", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveEmptyIndex(t *testing.T) {
+	for _, target := range []string{"latest", "stable", "oldstable"} {
+		if _, err := resolve(nil, target); err == nil {
+			t.Errorf("resolve(nil, %q) succeeded, want an error", target)
+		}
+	}
+}
+
+func TestNewest(t *testing.T) {
+	tests := []struct {
+		name     string
+		releases []Release
+		keep     func(Release) bool
+		want     string
+		found    bool
+	}{
+		{
+			name:     "any",
+			releases: testReleases,
+			keep:     func(Release) bool { return true },
+			want:     "go1.24rc1",
+			found:    true,
+		},
+		{
+			name:     "stable only",
+			releases: testReleases,
+			keep:     func(rel Release) bool { return rel.Stable },
+			want:     "go1.23.2",
+			found:    true,
+		},
+		{
+			name:     "unordered",
+			releases: []Release{{Version: "go1.9", Stable: true}, {Version: "go1.10", Stable: true}, {Version: "go1.9.7", Stable: true}},
+			keep:     func(Release) bool { return true },
+			want:     "go1.10",
+			found:    true,
+		},
+		{
+			name:     "stable wins a tie",
+			releases: []Release{{Version: "go1.22.0", Stable: false}, {Version: "1.22.0", Stable: true}},
+			keep:     func(Release) bool { return true },
+			want:     "1.22.0",
+			found:    true,
+		},
+		{
+			name:     "nothing kept",
+			releases: testReleases,
+			keep:     func(Release) bool { return false },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := newest(tt.releases, tt.keep)
+			if found != tt.found {
+				t.Fatalf("newest() found = %v, want %v", found, tt.found)
+			}
+			if got.Version != tt.want {
+				t.Errorf("newest() = %q, want %q", got.Version, tt.want)
+			}
+		})
+	}
+}
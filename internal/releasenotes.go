@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"fmt"
+)
+
+// releaseNotes are the notable language, tooling and standard library
+// changes of every minor go release, shown in the pull request of a bump
+// crossing them. Releases missing here only get a link to the full notes.
+var releaseNotes = map[string][]string{
+	"1.11": {
+		"Preliminary support for modules, the go.mod file and `GO111MODULE`",
+		"Experimental WebAssembly port (`GOOS=js GOARCH=wasm`)",
+		"Debug information is compressed, improved debugging of optimized binaries",
+	},
+	"1.12": {
+		"Opt-in TLS 1.3 in crypto/tls",
+		"The `go` directive of go.mod records the language version of the module",
+		"fmt prints maps sorted by key",
+		"`strings.ReplaceAll`, `bytes.ReplaceAll` and `os.UserHomeDir`",
+	},
+	"1.13": {
+		"Binary `0b`, octal `0o` and hexadecimal floating point literals, `_` digit separators",
+		"Signed shift counts",
+		"Error wrapping: `errors.Is`, `errors.As`, `errors.Unwrap` and `%w` in `fmt.Errorf`",
+		"`GOPROXY` defaults to proxy.golang.org and modules are checked against sum.golang.org",
+		"TLS 1.3 is enabled by default",
+	},
+	"1.14": {
+		"Embedded interfaces may have overlapping method sets",
+		"Goroutines are asynchronously preemptible",
+		"`defer` has almost no overhead",
+		"The vendor directory is used automatically when present and go.mod says go 1.14 or later",
+		"`testing.T.Cleanup` and the hash/maphash package",
+	},
+	"1.15": {
+		"Smaller binaries and a faster linker",
+		"X.509 certificates relying on CommonName are rejected by default",
+		"time/tzdata embeds the timezone database",
+		"`GOMODCACHE` sets the module cache location",
+	},
+	"1.16": {
+		"The embed package and `//go:embed` directives",
+		"The io/fs package; io/ioutil is deprecated in favour of io and os",
+		"Module mode is the default and `go install pkg@version` installs binaries",
+		"Native support for darwin/arm64",
+		"Builds no longer update go.mod and go.sum implicitly",
+	},
+	"1.17": {
+		"Module graph pruning: go.mod of go 1.17 modules lists every indirect dependency",
+		"`//go:build` lines replace `// +build`",
+		"Conversion from slice to array pointer, `unsafe.Add` and `unsafe.Slice`",
+		"Register based calling convention on amd64, about 5% faster",
+	},
+	"1.18": {
+		"Generics: type parameters, constraints, `any` and `comparable`",
+		"Native fuzzing in `go test`",
+		"Workspaces with `go work`",
+		"The net/netip package",
+	},
+	"1.19": {
+		"Revised memory model and typed atomics such as `atomic.Int64` and `atomic.Pointer`",
+		"Soft memory limit with `GOMEMLIMIT` and `debug.SetMemoryLimit`",
+		"Doc comments support links, lists and headings, gofmt reformats them",
+		"os/exec no longer runs binaries found through relative PATH entries",
+	},
+	"1.20": {
+		"Conversion from slice to array",
+		"`errors.Join` and errors wrapping several errors",
+		"Coverage profiles of whole programs with `go build -cover`",
+		"Preview of profile-guided optimization",
+		"The crypto/ecdh package, math/rand is seeded randomly",
+	},
+	"1.21": {
+		"The `min`, `max` and `clear` builtins",
+		"The log/slog, slices, maps and cmp packages",
+		"The `go` line is a minimum requirement and toolchains are managed with `toolchain` and `GOTOOLCHAIN`",
+		"Profile-guided optimization is generally available",
+	},
+	"1.22": {
+		"Every iteration of a `for` loop has its own variables",
+		"`range` over integers",
+		"Routing patterns with methods and wildcards in net/http's `ServeMux`",
+		"The math/rand/v2 package",
+	},
+	"1.23": {
+		"`range` over iterator functions, the iter package and iterators in slices and maps",
+		"The unique package",
+		"Unreferenced timers and tickers are garbage collected, their channels are unbuffered",
+		"Opt-in toolchain telemetry",
+	},
+	"1.24": {
+		"Generic type aliases",
+		"`tool` directives in go.mod",
+		"Swiss table based maps and a faster runtime",
+		"`os.Root` for filesystem access confined to a directory",
+		"`testing.B.Loop`, the weak package and `runtime.AddCleanup`",
+		"`omitzero` in encoding/json",
+	},
+	"1.25": {
+		"`GOMAXPROCS` respects container CPU limits",
+		"The testing/synctest package",
+		"`sync.WaitGroup.Go`",
+		"Experimental Green Tea garbage collector with `GOEXPERIMENT=greenteagc`",
+	},
+	"1.26": {
+		"`new` accepts an expression for the initial value: `new(f())`",
+		"Generic types may refer to themselves in their type parameter list",
+		"The Green Tea garbage collector is on by default",
+		"`go fix` applies the modernizers of the analysis framework",
+		"Cheaper cgo calls",
+		"The crypto/hpke package and `errors.AsType`",
+	},
+	"1.27": {
+		"The Green Tea garbage collector can no longer be turned off",
+		"macOS 13 Ventura or later is required",
+	},
+}
+
+type releaseNote struct {
+	version string
+	notes   []string
+}
+
+func (n releaseNote) url() string {
+	return fmt.Sprintf("https://go.dev/doc/go%s", n.version)
+}
+
+// releaseNotesBetween lists the minor releases after from up to and
+// including to, oldest first.
+func releaseNotesBetween(from, to string) []releaseNote {
+	fromParts, toParts := versionParts(from), versionParts(to)
+	if len(fromParts) < 2 || len(toParts) < 2 || fromParts[0] != toParts[0] {
+		return nil
+	}
+
+	var result []releaseNote
+	for minor := fromParts[1] + 1; minor <= toParts[1]; minor++ {
+		version := fmt.Sprintf("%d.%d", toParts[0], minor)
+		result = append(result, releaseNote{version: version, notes: releaseNotes[version]})
+	}
+
+	return result
+}
@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const maxBackoff = time.Minute
+
+// Retry configures the retries of transient failures of clones, fetches,
+// pushes and provider API calls, Attempts counting the first try. Backoff
+// is the delay before the first retry, doubled for every further one.
+// RateLimit caps the provider API calls changing something, such as
+// opening a pull request, per second over all repositories.
+type Retry struct {
+	Attempts  int           `yaml:"attempts"`
+	Backoff   time.Duration `yaml:"backoff"`
+	RateLimit float64       `yaml:"rate_limit"`
+}
+
+// retryableError is a failure worth retrying, after is how long the
+// server asked to wait.
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+func transient(err error, after time.Duration) error {
+	return &retryableError{err: err, after: after}
+}
+
+// retry calls f until it succeeds, fails permanently or the attempts are
+// used up, backing off exponentially with jitter between the attempts.
+func retry(ctx context.Context, cfg Retry, op string, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+
+		var r *retryableError
+		if err == nil || !errors.As(err, &r) || attempt >= cfg.Attempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay := backoff(cfg.Backoff, attempt)
+		if r.after > delay {
+			delay = r.after
+		}
+
+		loggerFrom(ctx).Warn("retrying", "op", op, "attempt", attempt, "in", roundDuration(delay), "err", err)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff doubles base for every attempt and picks a delay between half
+// and all of it, so parallel retries spread out.
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	d := base << uint(attempt-1)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// transientGitErrors are the messages of git failing on the network rather
+// than on the repository or the credentials.
+var transientGitErrors = []string{
+	"could not resolve host",
+	"connection timed out",
+	"connection reset",
+	"connection refused",
+	"no such host",
+	"i/o timeout",
+	"operation timed out",
+	"failed to connect",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"rpc failed",
+	"gnutls_handshake",
+	"tls handshake",
+	"internal server error",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+	"error: 429",
+	"error: 502",
+	"error: 503",
+	"error: 504",
+}
+
+// gitFailure marks the failure of a git command talking to a remote as
+// retryable when its output says the network is to blame.
+func gitFailure(err error, output []byte) error {
+	out := strings.ToLower(string(output))
+	for _, msg := range transientGitErrors {
+		if strings.Contains(out, msg) {
+			return transient(err, 0)
+		}
+	}
+
+	return err
+}
+
+// notSent tells whether a request failed before it reached the server, so
+// that even a request which isn't idempotent can be sent again.
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.As(err, &dnsErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "connection refused") || strings.Contains(msg, "no such host")
+}
+
+// permanent drops the retryable mark of err.
+func permanent(err error) error {
+	var r *retryableError
+	if errors.As(err, &r) {
+		return r.err
+	}
+
+	return err
+}
+
+// rateLimiter spaces out the API calls changing something and holds every
+// call back while the provider says the rate limit is exhausted. It is
+// shared by all repositories.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+	until    time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	l := &rateLimiter{}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+
+	return l
+}
+
+func (l *rateLimiter) wait(ctx context.Context, write bool) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := now
+	if l.until.After(at) {
+		at = l.until
+	}
+	if write && l.interval > 0 {
+		if l.next.After(at) {
+			at = l.next
+		}
+		l.next = at.Add(l.interval)
+	}
+	l.mu.Unlock()
+
+	return sleep(ctx, at.Sub(now))
+}
+
+// pause holds every call back for d.
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.until) {
+		l.until = until
+	}
+}
+
+// rateLimitWait reads how long to wait from the Retry-After header, or
+// from the reset time of an exhausted rate limit. It is 0 when the headers
+// say nothing.
+func rateLimitWait(header http.Header) time.Duration {
+	if after := header.Get("Retry-After"); after != "" {
+		if seconds, err := strconv.Atoi(after); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(after); err == nil {
+			return time.Until(at)
+		}
+	}
+
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Until(time.Unix(reset, 0))
+		}
+	}
+
+	return 0
+}
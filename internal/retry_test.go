@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	failure := errors.New("boom")
+
+	tests := []struct {
+		name     string
+		attempts int
+		errs     []error
+		calls    int
+		err      error
+	}{
+		{name: "succeeds", attempts: 3, calls: 1},
+		{name: "transient then succeeds", attempts: 3, errs: []error{transient(failure, 0)}, calls: 2},
+		{name: "attempts used up", attempts: 3, errs: []error{transient(failure, 0), transient(failure, 0), transient(failure, 0), nil}, calls: 3, err: failure},
+		{name: "permanent", attempts: 3, errs: []error{failure, nil}, calls: 1, err: failure},
+		{name: "no attempts configured", errs: []error{transient(failure, 0), nil}, calls: 1, err: failure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retry(context.Background(), Retry{Attempts: tt.attempts}, "test", func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Errorf("retry() = %v, want %v", err, tt.err)
+			}
+			if calls != tt.calls {
+				t.Errorf("retry() called f %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := retry(ctx, Retry{Attempts: 5, Backoff: time.Hour}, "test", func() error {
+		calls++
+		cancel()
+		return transient(errors.New("boom"), 0)
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("retry() = %v after %d calls, want the failure after 1", err, calls)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		min    time.Duration
+		max    time.Duration
+	}{
+		{name: "no headers", header: http.Header{}},
+		{name: "retry after seconds", header: http.Header{"Retry-After": {"30"}}, min: 30 * time.Second, max: 30 * time.Second},
+		{
+			name:   "retry after date",
+			header: http.Header{"Retry-After": {time.Now().Add(2 * time.Minute).UTC().Format(http.TimeFormat)}},
+			min:    time.Minute,
+			max:    2 * time.Minute,
+		},
+		{
+			name:   "exhausted rate limit",
+			header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {reset}},
+			min:    58 * time.Second,
+			max:    time.Minute,
+		},
+		{name: "remaining rate limit", header: http.Header{"X-Ratelimit-Remaining": {"10"}, "X-Ratelimit-Reset": {reset}}},
+		{name: "invalid retry after", header: http.Header{"Retry-After": {"soon"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if wait := rateLimitWait(tt.header); wait < tt.min || wait > tt.max {
+				t.Errorf("rateLimitWait() = %v, want between %v and %v", wait, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestGitFailure(t *testing.T) {
+	tests := []struct {
+		output    string
+		transient bool
+	}{
+		{output: "fatal: unable to access 'https://github.com/a/b/': Could not resolve host: github.com", transient: true},
+		{output: "error: RPC failed; HTTP 502 curl 22 The requested URL returned error: 502", transient: true},
+		{output: "fatal: the remote end hung up unexpectedly", transient: true},
+		{output: "dial tcp: lookup api.github.com: no such host", transient: true},
+		{output: "remote: Permission to a/b.git denied to c.\nfatal: unable to access: The requested URL returned error: 403"},
+		{output: " ! [rejected]        gobump -> gobump (stale info)"},
+		{output: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			var r *retryableError
+			err := gitFailure(errors.New("push failed"), []byte(tt.output))
+			if errors.As(err, &r) != tt.transient {
+				t.Errorf("gitFailure(%q) = %#v, want transient %v", tt.output, err, tt.transient)
+			}
+		})
+	}
+}
+
+func TestNotSent(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		notSent bool
+	}{
+		{name: "connection refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), notSent: true},
+		{name: "connection refused by hub", err: errors.New("hub api POST x: exit status 1\ndial tcp 127.0.0.1:443: connect: connection refused"), notSent: true},
+		{name: "unknown host", err: errors.New("dial tcp: lookup api.github.com: no such host"), notSent: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET)},
+		{name: "timeout", err: errors.New("context deadline exceeded (Client.Timeout exceeded while awaiting headers)")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notSent(tt.err); got != tt.notSent {
+				t.Errorf("notSent(%v) = %v, want %v", tt.err, got, tt.notSent)
+			}
+		})
+	}
+}
+
+// fakeAPI answers the calls with the responses in order, a nil response
+// is the error instead. Requests records the method and path of every call,
+// bodies what was sent.
+type fakeAPI struct {
+	responses []*apiResponse
+	errs      []error
+	calls     int
+	requests  []string
+	bodies    []string
+}
+
+func (f *fakeAPI) do(ctx context.Context, host, method, path string, body []byte) (*apiResponse, error) {
+	i := f.calls
+	f.calls++
+	f.requests = append(f.requests, method+" "+path)
+	f.bodies = append(f.bodies, string(body))
+	if i >= len(f.responses) {
+		return &apiResponse{status: http.StatusOK, header: http.Header{}}, nil
+	}
+	if f.responses[i] == nil {
+		return nil, f.errs[i]
+	}
+
+	return f.responses[i], nil
+}
+
+func TestAPIClientCallRetries(t *testing.T) {
+	status := func(code int) *apiResponse {
+		return &apiResponse{status: code, header: http.Header{}}
+	}
+	reset := transient(fmt.Errorf("read: %w", syscall.ECONNRESET), 0)
+	refused := transient(fmt.Errorf("dial: %w", syscall.ECONNREFUSED), 0)
+
+	tests := []struct {
+		name      string
+		method    string
+		responses []*apiResponse
+		errs      []error
+		calls     int
+		fails     bool
+	}{
+		{name: "GET server error", method: http.MethodGet, responses: []*apiResponse{status(502)}, calls: 2},
+		{name: "GET connection reset", method: http.MethodGet, responses: []*apiResponse{nil}, errs: []error{reset}, calls: 2},
+		{name: "PUT server error", method: http.MethodPut, responses: []*apiResponse{status(503)}, calls: 2},
+		{name: "POST server error", method: http.MethodPost, responses: []*apiResponse{status(502)}, calls: 1, fails: true},
+		{name: "POST connection reset", method: http.MethodPost, responses: []*apiResponse{nil}, errs: []error{reset}, calls: 1, fails: true},
+		{name: "POST connection refused", method: http.MethodPost, responses: []*apiResponse{nil}, errs: []error{refused}, calls: 2},
+		{name: "POST too many requests", method: http.MethodPost, responses: []*apiResponse{status(429)}, calls: 2},
+		{
+			name:      "POST rate limited",
+			method:    http.MethodPost,
+			responses: []*apiResponse{{status: 403, header: http.Header{}, body: []byte(`{"message":"API rate limit exceeded"}`)}},
+			calls:     2,
+		},
+		{name: "POST forbidden", method: http.MethodPost, responses: []*apiResponse{status(403)}, calls: 1, fails: true},
+		{name: "GET not found", method: http.MethodGet, responses: []*apiResponse{status(404)}, calls: 1, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{responses: tt.responses, errs: tt.errs}
+			c := newAPIClient(api, Retry{Attempts: 3})
+			err := c.call(context.Background(), "github.com", tt.method, "repos/a/b/pulls", nil, nil)
+			if (err != nil) != tt.fails {
+				t.Errorf("call() = %v, want failure %v", err, tt.fails)
+			}
+			if api.calls != tt.calls {
+				t.Errorf("call() sent %d requests, want %d", api.calls, tt.calls)
+			}
+		})
+	}
+}
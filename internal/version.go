@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares go versions such as `1.13`, `1.21.0` or `go1.22.1`.
+// A missing patch component sorts before any explicit one.
+func compareVersions(a, b string) int {
+	av, bv := versionParts(a), versionParts(b)
+	for i := 0; i < len(av) || i < len(bv); i++ {
+		x, y := -1, -1
+		if i < len(av) {
+			x = av[i]
+		}
+		if i < len(bv) {
+			y = bv[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "go")
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		end := 0
+		for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+			end++
+		}
+		n, err := strconv.Atoi(p[:end])
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+		if end < len(p) {
+			break
+		}
+	}
+
+	return parts
+}
@@ -1,14 +1,18 @@
 package internal
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gammazero/workerpool"
 )
@@ -17,57 +21,94 @@ const (
 	goMod = "go.mod"
 )
 
-type controller interface {
-	Submit() error
-	Prepare() error
-	Cleanup() error
-}
-
 type file struct {
 	path string
 	name string
 }
 
 type Worker struct {
-	path        string
-	version     string
-	currentGo   string
-	files       []file
-	vController controller
+	path         string
+	version      string
+	currentGo    string
+	files        []file
+	cfg          Config
+	repoCfg      RepoConfig
+	api          *apiLoader
+	depChanges   []DepChange
+	campaign     *Campaign
+	remotes      map[string]string
+	reviewer     *reviewer
+	extraEditors []Editor
+	vcs          func(dir string) VCS
+	provider     Provider
+	log          *slog.Logger
+	progress     *Progress
+	stage        string
+	verification []verification
 }
 
-func NewWorker(path, version string) Worker {
-	return Worker{
-		path:        path,
-		version:     version,
+func NewWorker(path string, cfg Config) Worker {
+	w := Worker{
+		path:    path,
+		version: cfg.Version,
+		cfg:     cfg,
+		api:     &apiLoader{},
+		log:     slog.Default(),
+		vcs: func(dir string) VCS {
+			vc := NewWorkerVC(dir, cfg.Commit)
+			return &vc
+		},
+	}
+
+	if cfg.Interactive {
+		w.reviewer = newReviewer(os.Stdin, os.Stdout)
 	}
+
+	return w
 }
 
-func (w Worker) Init() {
-	var wg sync.WaitGroup
-	wp := workerpool.New(30)
-	repos, err := w.repos()
+// UseCampaign records the progress of every repository in the campaign and
+// skips the ones it already completed.
+func (w *Worker) UseCampaign(c *Campaign) {
+	w.campaign = c
+}
 
-	if err != nil {
-		haltOnError(err)
-	}
+// UseEditors adds editors run after the built-in ones.
+func (w *Worker) UseEditors(editors ...Editor) {
+	w.extraEditors = append(w.extraEditors, editors...)
+}
 
-	for _, v := range repos{
-		path := filepath.Join(w.path, v)
-		fmt.Println(path)
-		wg.Add(1)
-		wp.Submit(func() {
-			w.bump(&wg, path)
+// UseVCS replaces the hub based VCS, newVCS is called with every checkout.
+func (w *Worker) UseVCS(newVCS func(dir string) VCS) {
+	w.vcs = newVCS
+}
 
-		})
-	}
+// UseProvider replaces the provider named in the config.
+func (w *Worker) UseProvider(p Provider) {
+	w.provider = p
+}
 
-	wg.Wait()
-	wp.Stop()
+// UseProgress reports the stage of every repository to the progress view.
+func (w *Worker) UseProgress(p *Progress) {
+	w.progress = p
+}
+
+// UseLogger replaces the default slog logger.
+func (w *Worker) UseLogger(l *slog.Logger) {
+	w.log = l
 }
 
 func (w Worker) repos() ([]string, error) {
 	var repos []string
+	if w.remotes != nil {
+		for name := range w.remotes {
+			repos = append(repos, name)
+		}
+		sort.Strings(repos)
+		w.log.Info("found repositories", "count", len(repos))
+		return repos, nil
+	}
+
 	files, err := ioutil.ReadDir(w.path)
 	if err != nil {
 		return repos, err
@@ -77,146 +118,465 @@ func (w Worker) repos() ([]string, error) {
 			repos = append(repos, file.Name())
 		}
 	}
-	fmt.Println("counting files ", len(repos))
+	w.log.Info("found repositories", "count", len(repos), "path", w.path)
 	return repos, nil
 }
 
-// TODO: check if hub installed
-// TODO: compare values of old vs new go version
-func (w Worker) bump(wg *sync.WaitGroup, path string) {
-	vCli := NewWorkerVC(path)
-	w.vController = &vCli
-	//if err := w.vController.Prepare(); err != nil {
-	//	haltOnError(err)
-	//}
+// checkout prepares the directory a repository is bumped in: a fresh clone
+// of remote repositories, a worktree with --worktree or else the local
+// checkout itself. cleanup removes whatever was created for the bump.
+func (w Worker) checkout(ctx context.Context, rp RepoPlan) (dir string, cleanup func(), err error) {
+	if rp.Remote != "" {
+		workspace, err := ioutil.TempDir("", "gobump")
+		if err != nil {
+			return "", nil, err
+		}
+
+		dir := filepath.Join(workspace, rp.Name)
+		err = retry(ctx, w.cfg.Retry, "clone", func() error {
+			return clone(ctx, rp.Remote, dir)
+		})
+		if err != nil {
+			os.RemoveAll(workspace)
+			return "", nil, err
+		}
+
+		return dir, func() { os.RemoveAll(workspace) }, nil
+	}
+
+	if w.cfg.Worktree {
+		var wt *worktree
+		err := retry(ctx, w.cfg.Retry, "fetch", func() (err error) {
+			wt, err = addWorktree(ctx, rp.Dir)
+			return err
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		return wt.path, func() {
+			// the worktree is removed even when the bump was cancelled
+			if err := wt.remove(context.WithoutCancel(ctx)); err != nil {
+				w.log.Warn("removing worktree", "path", wt.path, "err", err)
+			}
+		}, nil
+	}
+
+	return rp.Dir, func() {}, nil
+}
 
-	if err := filepath.Walk(path, w.visit); err != nil {
-		haltOnError(err)
+// Apply carries out the plan and reports the outcome of every repository.
+// Failures are only reported, the remaining repositories are still bumped.
+func (w Worker) Apply(ctx context.Context, plan *Plan) ([]Result, error) {
+	if w.provider == nil {
+		p, err := newProvider(w.cfg)
+		if err != nil {
+			return nil, err
+		}
+		w.provider = p
 	}
 
-	// no go.mod with version exit
-	if w.currentGo == "" {
-		return
+	w.progress.setTotal(len(plan.Repos))
+	results := make([]Result, len(plan.Repos))
+	var wg sync.WaitGroup
+	wp := workerpool.New(w.cfg.Concurrency)
+	for i, rp := range plan.Repos {
+		i, rp := i, rp
+		wg.Add(1)
+		wp.Submit(func() {
+			defer wg.Done()
+			results[i] = w.applyRepo(ctx, rp)
+		})
 	}
 
-	if err := w.vendor(path); err != nil {
-		haltOnError(err)
+	wg.Wait()
+	wp.Stop()
+	return results, ctx.Err()
+}
+
+// enter records the stage the repository is in for the progress view and
+// the logs.
+func (w *Worker) enter(repo, stage string) {
+	w.stage = stage
+	w.progress.stage(repo, stage)
+}
+
+// skipped is returned by apply when a repository is deliberately left alone.
+type skipped string
+
+func (s skipped) Error() string {
+	return string(s)
+}
+
+// deferred is returned when the review leaves a repository for later: it is
+// reported as skipped but stays pending, so resuming the campaign revisits it.
+type deferred string
+
+func (d deferred) Error() string {
+	return string(d)
+}
+
+func (w Worker) applyRepo(ctx context.Context, rp RepoPlan) Result {
+	res := Result{Repo: rp.Name}
+	w.log = w.log.With("repo", rp.Name)
+
+	ctx, closeLog, err := w.openLog(ctx, rp.Name)
+	if err != nil {
+		w.log.Error("opening log file", "err", err)
+		ctx, closeLog = context.WithValue(ctx, commandLogKey{}, &commandLog{logger: w.log}), func() {}
+	}
+	defer closeLog()
+	defer func() { w.progress.finish(rp.Name, res.Status) }()
+
+	switch {
+	case rp.Skip != "":
+		res.Err = skipped(rp.Skip)
+	case rp.Error != "":
+		res.Err = errors.New(rp.Error)
+	case w.reviewer.quitting():
+		res.Err = deferred("review quit")
+	case ctx.Err() != nil:
+		res.Err = ctx.Err()
+	default:
+		w.log.Info("bumping", "stage", stageDiscovered, "from", rp.From, "to", rp.To)
+		w.campaign.discover(rp.Name)
+		res.PR, res.Err = w.apply(ctx, rp)
 	}
 
-	if err := w.visitGitHub(); err != nil {
-		haltOnError(err)
+	if reason, ok := res.Err.(skipped); ok {
+		w.log.Info("skipped", "reason", string(reason))
+		res.Status, res.Reason, res.Err = statusSkipped, string(reason), nil
+		if !w.campaign.completed(rp.Name) {
+			w.campaign.skip(rp.Name, res.Reason)
+		}
+		return res
 	}
 
-	// TODO: check if hub installed
-	if err := w.vController.Submit(); err != nil {
-		fmt.Println("submit")
-		haltOnError(err)
+	if reason, ok := res.Err.(deferred); ok {
+		w.log.Info("deferred", "reason", string(reason))
+		res.Status, res.Reason, res.Err = statusSkipped, string(reason), nil
+		w.campaign.postpone(rp.Name, res.Reason)
+		return res
 	}
 
-	//if err := w.vController.Cleanup(); err != nil {
-	//	fmt.Println("cleanup")
-	//	haltOnError(err)
-	//}
+	if res.Err != nil {
+		w.log.Error("failed", "stage", w.stage, "err", res.Err)
+		res.Status, res.Reason = statusFailed, Redact(res.Err.Error())
+		w.campaign.fail(rp.Name, res.Err)
+		return res
+	}
 
-	wg.Done()
+	w.log.Info("done", "stage", stagePR, "pr", res.PR)
+	res.Status = statusDone
+	return res
 }
 
-func (w *Worker) visit(path string, fi os.FileInfo, err error) error {
+func (w *Worker) apply(ctx context.Context, rp RepoPlan) (string, error) {
+	dir, cleanup, err := w.checkout(ctx, rp)
 	if err != nil {
-		return err
+		return "", err
 	}
+	defer cleanup()
 
-	if fi.IsDir() && fi.Name() == "vendor" {
-		return filepath.SkipDir
+	if w.repoCfg, err = LoadRepoConfig(dir); err != nil {
+		return "", err
+	}
+	w.currentGo, w.version = rp.From, rp.To
+
+	// changes committed by an earlier run of the campaign are only submitted,
+	// unless they were committed in a clone or worktree which is gone by now
+	state := w.campaign.state(rp.Name)
+	ephemeral := rp.Remote != "" || w.cfg.Worktree
+	if state.Stage == stagePushed || (state.Stage == stageCommitted && !ephemeral) {
+		w.depChanges = state.Deps
+		return w.submit(ctx, dir, rp, state.Stage)
 	}
 
-	if fi.IsDir() && fi.Name() == ".git" {
-		return filepath.SkipDir
+	// a skipped review reverts the whole tree, which mustn't take the user's
+	// own changes with it
+	fresh := state.Stage == "" || state.Stage == stageDiscovered
+	if w.reviewer != nil && fresh && rp.Remote == "" && !w.cfg.Worktree {
+		status, err := git(ctx, dir, "status", "--porcelain")
+		if err != nil {
+			return "", err
+		}
+		if status != "" {
+			return "", fmt.Errorf("%s has uncommitted changes, commit or stash them or review in a --worktree", dir)
+		}
 	}
 
-	w.files = append(w.files, file{path: path, name: fi.Name()})
+	// committing in the worktree resets the branch, don't lose the user's one
+	if w.cfg.Worktree && rp.Remote == "" {
+		if _, err := git(ctx, rp.Dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+rp.Branch); err == nil {
+			return "", fmt.Errorf("local branch %s already exists in %s, delete it to bump in a worktree", rp.Branch, rp.Dir)
+		}
+	}
 
-	if fi.IsDir() {
-		return nil
+	if err := checkPlanned(dir, rp); err != nil {
+		return "", err
 	}
 
-	matched, err := filepath.Match("go.mod", fi.Name())
-	if err != nil {
-		return err
+	w.enter(rp.Name, progressEdit)
+	env := hookEnv{repo: rp.Name, from: rp.From, to: rp.To}
+	if err := w.runHooks(ctx, hookPreEdit, dir, env); err != nil {
+		return "", err
 	}
 
-	if matched {
-		if err := w.editFile(path); err != nil {
-			return err
+	for _, e := range rp.Edits {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(e.Path)), []byte(e.Content), 0); err != nil {
+			return "", err
 		}
 	}
 
-	return nil
-}
+	if w.cfg.Deps.Mode != "" {
+		if err := w.updateDeps(ctx, dir); err != nil {
+			return "", err
+		}
+	}
+
+	w.enter(rp.Name, progressVendor)
+	if err := w.vendor(ctx, dir); err != nil {
+		return "", err
+	}
+
+	if err := w.runHooks(ctx, hookPostEdit, dir, env); err != nil {
+		return "", err
+	}
+
+	w.campaign.edited(rp.Name, rp.From, rp.To, w.depChanges)
+	w.log.Info("edited", "stage", stageEdited, "files", len(rp.Edits), "deps", len(w.depChanges))
 
-func (w *Worker) visitGitHub() error {
-	for _, file := range w.files {
-		matched, err := filepath.Match("*.yaml", file.name)
+	w.enter(rp.Name, progressVerify)
+	verifyErr := w.verify(ctx, dir)
+	if w.reviewer != nil {
+		apply, err := w.review(ctx, rp.Name, dir, verifyErr)
 		if err != nil {
-			return err
+			return "", err
 		}
-
-		if matched {
-			if err := w.editFile(file.path); err != nil {
-				return err
-			}
+		if !apply {
+			return "", deferred("skipped in review")
 		}
+	} else if verifyErr != nil {
+		return "", verifyErr
 	}
 
-	return nil
+	w.campaign.update(rp.Name, stageVerified)
+	w.log.Info("verified", "stage", stageVerified)
+
+	return w.submit(ctx, dir, rp, stageVerified)
 }
 
-func (w *Worker) editFile(path string) error {
-	read, err := ioutil.ReadFile(path)
+// submit commits, pushes and opens the pull request, starting after the
+// given stage.
+func (w *Worker) submit(ctx context.Context, dir string, rp RepoPlan, stage string) (string, error) {
+	env := hookEnv{repo: rp.Name, from: rp.From, to: rp.To}
+	vcs := w.vcs(dir)
+
+	// the files changed besides the planned edits are only known before
+	// they are committed
+	verified := stage == stageVerified
+	var changed []string
+	if verified {
+		var err error
+		if changed, err = changedFiles(ctx, dir); err != nil {
+			w.log.Debug("listing changed files failed", "err", err)
+		}
+	}
+
+	if stage == stageVerified {
+		w.enter(rp.Name, progressCommit)
+		if err := w.runHooks(ctx, hookPreCommit, dir, env); err != nil {
+			return "", err
+		}
+
+		if err := vcs.Commit(ctx, rp.Branch, rp.Commit); err != nil {
+			return "", err
+		}
+		w.campaign.update(rp.Name, stageCommitted)
+		w.log.Info("committed", "stage", stageCommitted, "branch", rp.Branch)
+		stage = stageCommitted
+	}
+
+	push := vcs.Push
+	var fork Fork
+	if w.cfg.Fork != "" {
+		pusher, ok := vcs.(RemotePusher)
+		if !ok {
+			return "", fmt.Errorf("fork mode: the VCS can't push to the fork")
+		}
+
+		var err error
+		if fork, err = w.fork(ctx, dir, rp.Branch); err != nil {
+			return "", err
+		}
+		defer w.removeForkRemote(ctx, dir)
+
+		push = func(ctx context.Context, branch string) error {
+			return pusher.PushTo(ctx, forkRemote, branch)
+		}
+	}
+
+	if stage == stageCommitted {
+		w.enter(rp.Name, progressPush)
+		err := retry(ctx, w.cfg.Retry, "push", func() error {
+			return push(ctx, rp.Branch)
+		})
+		if err != nil {
+			return "", err
+		}
+		w.campaign.update(rp.Name, stagePushed)
+		w.log.Info("pushed", "stage", stagePushed, "branch", rp.Branch)
+	}
+
+	w.enter(rp.Name, progressPR)
+	description := w.describe(rp, changed, verified)
+	body, err := render(w.cfg.Templates.Body, templateData{
+		Repo:        rp.Name,
+		From:        rp.From,
+		To:          rp.To,
+		Deps:        w.depChanges,
+		Description: description,
+	})
 	if err != nil {
-		return err
+		return "", err
+	}
+	if strings.TrimSpace(body) == "" {
+		body = description
 	}
 
-	if strings.Contains(path, goMod) {
-		w.storeCurrentGoVersion(read)
+	pr, err := w.newPullRequest(dir, rp, changed)
+	if err != nil {
+		return "", err
 	}
 
-	replaced := bytes.Replace(read, []byte(w.currentGo), []byte(w.version), -1)
-	err = ioutil.WriteFile(path, replaced, 0)
+	pr.Title, pr.Body, pr.Head, pr.Fork = rp.Title, body, rp.Branch, fork.Path
 
+	url, err := w.provider.OpenPullRequest(ctx, dir, pr)
+	if err != nil && url == "" {
+		return "", err
+	}
+	// the pull request exists, failing the repository would make a resumed
+	// campaign open a second one. Options failing, such as labels without
+	// write access to upstream in fork mode, are only reported.
+	w.campaign.opened(rp.Name, url)
 	if err != nil {
-		return err
+		w.log.Warn("pull request opened without all its options", "url", url, "err", err)
 	}
 
-	return nil
+	env.pr = url
+	if err := w.runHooks(ctx, hookPostPR, dir, env); err != nil {
+		w.log.Warn("post-pr hook failed", "url", url, "err", err)
+	}
+
+	return url, nil
 }
 
-func (w *Worker) storeCurrentGoVersion(read []byte) {
-	// Find `go 1.13` index to extract go version
-	index := bytes.Index(read, []byte("go "))
-	if index != -1 {
-		index = index + 3
-		// TODO: leave naive approach
-		w.currentGo = bytes.NewBuffer(read[index : index+4]).String()
+func (w *Worker) visit(path string, fi os.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() && fi.Name() == "vendor" {
+		return filepath.SkipDir
+	}
+
+	if fi.IsDir() && fi.Name() == ".git" {
+		return filepath.SkipDir
+	}
+
+	if !fi.IsDir() {
+		w.files = append(w.files, file{path: path, name: fi.Name()})
 	}
+
+	return nil
 }
 
-func (w *Worker) vendor(path string) error {
-	cmd := exec.Command("go", "mod", "vendor")
+func (w *Worker) vendor(ctx context.Context, path string) error {
+	cmd := exec.CommandContext(ctx, "go", "mod", "vendor")
 	cmd.Dir = filepath.Join(path)
 
-	if err := cmd.Run(); err != nil {
-		return err
+	if output, err := run(ctx, cmd); err != nil {
+		return fmt.Errorf("go mod vendor: %v\n%s", err, output)
 	}
 
 	return nil
 }
 
-func haltOnError(err error) {
-	if err != nil {
-		if _, err := fmt.Fprintln(os.Stderr, err); err != nil {
-			fmt.Println(err)
+// newPullRequest fills in the labels, reviewers, assignees, milestone and
+// draft state of the repository's pull request, adding the code owners of
+// the changed files to the reviewers.
+func (w *Worker) newPullRequest(dir string, rp RepoPlan, changed []string) (NewPullRequest, error) {
+	opts := w.cfg.PullRequest.override(w.repoCfg.PullRequest)
+	if err := opts.validate(); err != nil {
+		return NewPullRequest{}, err
+	}
+
+	pr := NewPullRequest{
+		Draft:         opts.Draft != nil && *opts.Draft,
+		Labels:        opts.Labels,
+		Reviewers:     opts.Reviewers,
+		TeamReviewers: opts.TeamReviewers,
+		Assignees:     opts.Assignees,
+		Milestone:     opts.Milestone,
+		AutoMerge:     opts.AutoMerge,
+	}
+
+	if opts.IgnoreCodeOwners {
+		return pr, nil
+	}
+
+	rules, err := codeOwners(dir)
+	if err != nil || rules == nil {
+		return pr, err
+	}
+
+	files := append([]string(nil), changed...)
+	for _, e := range rp.Edits {
+		files = append(files, e.Path)
+	}
+	users, teams := owners(rules, files)
+	pr.Reviewers = appendMissing(pr.Reviewers, users...)
+	pr.TeamReviewers = appendMissing(pr.TeamReviewers, teams...)
+
+	return pr, nil
+}
+
+func appendMissing(list []string, add ...string) []string {
+	result := append([]string(nil), list...)
+	for _, a := range add {
+		found := false
+		for _, s := range result {
+			if strings.EqualFold(s, a) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, a)
+		}
+	}
+
+	return result
+}
+
+func (w *Worker) verify(ctx context.Context, path string) error {
+	commands := w.cfg.Verify
+	if len(w.repoCfg.Verify) > 0 {
+		commands = w.repoCfg.Verify
+	}
+
+	w.verification = nil
+	for _, command := range commands {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = filepath.Join(path)
+
+		started := time.Now()
+		output, err := run(ctx, cmd)
+		w.verification = append(w.verification, verification{command: command, err: err, took: time.Since(started)})
+		if err != nil {
+			return fmt.Errorf("%s: %v\n%s", command, err, output)
 		}
-		os.Exit(1)
 	}
+
+	return nil
 }
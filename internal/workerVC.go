@@ -1,197 +1,161 @@
 package internal
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 )
 
 const (
-	bumpBranch = "nextGo"
-	master     = "master"
+	master = "master"
+
+	signGPG = "gpg"
+	signSSH = "ssh"
 )
 
+// VCS records the change of a repository checkout on a branch and pushes
+// that branch to origin.
+type VCS interface {
+	Commit(ctx context.Context, branch, message string) error
+	Push(ctx context.Context, branch string) error
+}
+
+// RemotePusher is implemented by a VCS which can push to another remote
+// than origin, as fork mode pushes the branch to the fork.
+type RemotePusher interface {
+	PushTo(ctx context.Context, remote, branch string) error
+}
+
 type WorkerVC struct {
-	path      string
-	originalB string
+	path    string
+	branch  string
+	options Commit
 }
 
-func NewWorkerVC(path string) WorkerVC {
+func NewWorkerVC(path string, options Commit) WorkerVC {
 	return WorkerVC{
-		path: path,
+		path:    path,
+		options: options,
 	}
 }
 
-func (w *WorkerVC) Prepare() error {
-	if err := w.currentBranch(); err != nil {
-		return err
-	}
-
-	if err := w.stash(); err != nil {
-		return err
-	}
+func (w *WorkerVC) Commit(ctx context.Context, branch, message string) error {
+	w.branch = branch
 
-	if err := w.checkout(master); err != nil {
-		return err
+	if err := w.addChanges(ctx); err != nil {
+		return fmt.Errorf("add: %v", err)
 	}
 
-	if err := w.pull(); err != nil {
-		return err
+	if err := w.branchOut(ctx); err != nil {
+		return fmt.Errorf("branch out: %v", err)
 	}
 
-	return nil
-}
-
-func (w *WorkerVC) currentBranch() error {
-	cmd := exec.Command("hub", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = filepath.Join(w.path)
-	output, err := cmd.Output()
-	if err != nil {
-		return err
+	if err := w.commit(ctx, message); err != nil {
+		return fmt.Errorf("commit: %v", err)
 	}
 
-	w.originalB = string(output)
 	return nil
 }
 
-func (w *WorkerVC) stash() error {
-	cmd := exec.Command("hub", "stash")
-	cmd.Dir = filepath.Join(w.path)
-
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-	return nil
+func (w *WorkerVC) Push(ctx context.Context, branch string) error {
+	return w.PushTo(ctx, "origin", branch)
 }
 
-func (w *WorkerVC) checkout(branch string) error {
-	cmd := exec.Command("hub", "checkout", branch)
+func (w *WorkerVC) PushTo(ctx context.Context, remote, branch string) error {
+	// the bump branch belongs to gobump, a leftover from an earlier run is
+	// simply replaced
+	cmd := exec.CommandContext(ctx, "hub", "push", "--force-with-lease", "-u", remote, branch)
 	cmd.Dir = filepath.Join(w.path)
 
-	if err := cmd.Run(); err != nil {
-		return err
+	if output, err := run(ctx, cmd); err != nil {
+		return gitFailure(fmt.Errorf("push: %v\n%s", err, output), output)
 	}
 	return nil
 }
 
-func (w *WorkerVC) pull() error {
-	cmd := exec.Command("hub", "pull")
+func (w *WorkerVC) branchOut(ctx context.Context) error {
+	// -B so that a retried bump reuses its branch
+	cmd := exec.CommandContext(ctx, "hub", "checkout", "-B", w.branch)
 	cmd.Dir = filepath.Join(w.path)
 
-	if err := cmd.Run(); err != nil {
-		return err
+	if output, err := run(ctx, cmd); err != nil {
+		return fmt.Errorf("%v\n%s", err, output)
 	}
 	return nil
 }
 
-func (w *WorkerVC) Submit() error {
-	if err := w.addChanges(); err != nil {
-		fmt.Println("add", err)
-		return err
-	}
-
-	if err := w.branchOut(); err != nil {
-		fmt.Println("branch out ", err)
-		return err
+func (w *WorkerVC) commit(ctx context.Context, message string) error {
+	args := []string{"commit", "-am", message}
+	var config []string
+	switch w.options.Sign {
+	case "":
+	case signGPG:
+		args = append(args, "-S")
+		config = append(config, "gpg.format", "openpgp")
+	case signSSH:
+		args = append(args, "-S")
+		config = append(config, "gpg.format", "ssh")
+	default:
+		return w.options.validate()
 	}
-
-	if err := w.commit(); err != nil {
-		fmt.Println("commit ", err)
-		return err
-	}
-
-	if err := w.pr(); err != nil {
-		fmt.Println("pr ", err)
-		return err
-	}
-
-	return nil
-}
-
-func (w *WorkerVC) branchOut() error {
-	// TODO: issue when passing constant as a branch?
-	cmd := exec.Command("hub", "checkout", "-b", "next-Go")
-	cmd.Dir = filepath.Join(w.path)
-
-	if err := cmd.Run(); err != nil {
-		return err
+	if w.options.SigningKey != "" {
+		config = append(config, "user.signingkey", w.options.SigningKey)
 	}
-	return nil
-}
-
-func (w *WorkerVC) commit() error {
-	cmd := exec.Command("hub", "commit", `-am "bump go version with go-bump"`)
-	cmd.Dir = filepath.Join(w.path)
-
-	if err := cmd.Run(); err != nil {
-		return err
+	if w.options.Signoff {
+		args = append(args, "--signoff")
 	}
-	return nil
-}
 
-func (w *WorkerVC) pr() error {
-	cmd := exec.Command("hub",
-		"pull-request",
-		"-p",
-		"-l",
-		"minor",
-		"--no-edit",
-		"-m",
-		"update go version",
-	)
+	cmd := exec.CommandContext(ctx, "hub", args...)
 	cmd.Dir = filepath.Join(w.path)
+	cmd.Env = append(os.Environ(), w.identityEnv()...)
 
-	if err := cmd.Run(); err != nil {
-		return err
+	// passed through the environment as hub doesn't forward `git -c`
+	if len(config) > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(config)/2))
+		for i := 0; i < len(config); i += 2 {
+			cmd.Env = append(cmd.Env,
+				fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i/2, config[i]),
+				fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i/2, config[i+1]))
+		}
 	}
-	return nil
-}
-
-func (w *WorkerVC) addChanges() error {
-	cmd := exec.Command("hub", "add", ".")
-	cmd.Dir = filepath.Join(w.path)
 
-	if err := cmd.Run(); err != nil {
-		return err
+	if output, err := run(ctx, cmd); err != nil {
+		return fmt.Errorf("%v\n%s", err, output)
 	}
 	return nil
 }
 
-func (w *WorkerVC) Cleanup() error {
-	if err := w.checkout(w.originalB); err != nil {
-		fmt.Println("checkout")
-		return err
+// identityEnv sets the configured author and committer, the committer
+// defaults to the author.
+func (w *WorkerVC) identityEnv() []string {
+	author, committer := w.options.Author, w.options.Committer
+	if committer == (Identity{}) {
+		committer = author
 	}
 
-	if err := w.stashPop(); err != nil {
-		fmt.Println("stash pop")
-		return err
+	var env []string
+	for _, v := range []struct{ key, value string }{
+		{"GIT_AUTHOR_NAME", author.Name},
+		{"GIT_AUTHOR_EMAIL", author.Email},
+		{"GIT_COMMITTER_NAME", committer.Name},
+		{"GIT_COMMITTER_EMAIL", committer.Email},
+	} {
+		if v.value != "" {
+			env = append(env, v.key+"="+v.value)
+		}
 	}
 
-	if err := w.removeBranch(); err != nil {
-		fmt.Println("remove branch")
-		return err
-	}
-
-	return nil
-}
-
-func (w *WorkerVC) stashPop() error {
-	cmd := exec.Command("hub", "stash", "pop")
-	cmd.Dir = filepath.Join(w.path)
-
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-	return nil
+	return env
 }
 
-func (w *WorkerVC) removeBranch() error {
-	cmd := exec.Command("hub", "branch", "-D", bumpBranch)
+func (w *WorkerVC) addChanges(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "hub", "add", ".")
 	cmd.Dir = filepath.Join(w.path)
 
-	if err := cmd.Run(); err != nil {
-		return err
+	if output, err := run(ctx, cmd); err != nil {
+		return fmt.Errorf("%v\n%s", err, output)
 	}
 	return nil
 }
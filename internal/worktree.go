@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+)
+
+// worktree is a dedicated `git worktree` of a local repository, checked out
+// at the freshly fetched default branch, so the bump never touches the
+// user's branch, index or stash.
+type worktree struct {
+	repo     string
+	path     string
+	branches map[string]bool
+}
+
+func addWorktree(ctx context.Context, repo string) (*worktree, error) {
+	if _, err := git(ctx, repo, "fetch", "origin"); err != nil {
+		return nil, err
+	}
+
+	base, err := defaultBranch(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// branches which already existed are never deleted with the worktree
+	refs, err := git(ctx, repo, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	branches := map[string]bool{}
+	for _, ref := range strings.Fields(refs) {
+		branches[ref] = true
+	}
+
+	path, err := ioutil.TempDir("", "gobump-worktree")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := git(ctx, repo, "worktree", "add", "--detach", path, "origin/"+base); err != nil {
+		return nil, err
+	}
+
+	return &worktree{repo: repo, path: path, branches: branches}, nil
+}
+
+// remove drops the worktree together with the branch created in it, unless
+// the branch existed before the worktree.
+func (wt *worktree) remove(ctx context.Context) error {
+	branch, _ := git(ctx, wt.path, "symbolic-ref", "--quiet", "--short", "HEAD")
+
+	if _, err := git(ctx, wt.repo, "worktree", "remove", "--force", wt.path); err != nil {
+		return err
+	}
+
+	if branch != "" && !wt.branches[branch] {
+		if _, err := git(ctx, wt.repo, "branch", "-D", branch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// defaultBranch reads the branch origin/HEAD points to.
+func defaultBranch(ctx context.Context, repo string) (string, error) {
+	if ref, err := git(ctx, repo, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+
+	output, err := git(ctx, repo, "remote", "show", "origin")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "HEAD branch:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "HEAD branch:")), nil
+		}
+	}
+
+	return master, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	stdout, err := output(ctx, cmd)
+	if err != nil {
+		return "", gitFailure(fmt.Errorf("git %s: %v", strings.Join(args, " "), err), []byte(err.Error()))
+	}
+
+	return strings.TrimSpace(string(stdout)), nil
+}
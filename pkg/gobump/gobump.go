@@ -0,0 +1,274 @@
+// Package gobump bumps the go version of many repositories at once and
+// opens a pull request for each of them.
+//
+// A bump is planned first, which only reads the repositories, and the plan
+// is then applied:
+//
+//	b, err := gobump.New(gobump.Options{Path: "/src", Config: gobump.DefaultConfig()})
+//	plan, err := b.Plan(ctx)
+//	results, err := b.Apply(ctx, plan)
+//
+// Editors, the VCS and the provider can be replaced to bump other kinds of
+// files or to publish the change elsewhere.
+package gobump
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/jkonarze/gobump/internal"
+)
+
+type (
+	// Config holds the organisation wide defaults.
+	Config = internal.Config
+	// RepoConfig is the per-repository .gobump.yaml.
+	RepoConfig = internal.RepoConfig
+	// Templates render the branch, commit, title and body of the change.
+	Templates = internal.Templates
+	// Deps configures the dependency update run alongside the bump.
+	Deps = internal.Deps
+	// Hooks are shell commands run around the bump.
+	Hooks = internal.Hooks
+	// Commit configures the identity and signature of the bump commits.
+	Commit = internal.Commit
+	// PROptions are the labels, reviewers and the like of the pull requests.
+	PROptions = internal.PROptions
+	// Policy decides the target version of the repositories it matches.
+	Policy = internal.Policy
+	// Host configures a GitHub Enterprise or self-hosted GitLab host.
+	Host = internal.Host
+	// Identity is a git author or committer.
+	Identity = internal.Identity
+	// Log configures the logging and the per-repository log files.
+	Log = internal.Log
+	// Progress is a live terminal view of the bump.
+	Progress = internal.Progress
+
+	// Plan is the change a bump makes to every repository.
+	Plan = internal.Plan
+	// RepoPlan is the planned change of one repository.
+	RepoPlan = internal.RepoPlan
+	// FileEdit is the new content of a file.
+	FileEdit = internal.FileEdit
+	// Result is the outcome of applying the plan of a repository.
+	Result = internal.Result
+
+	// Editor rewrites one kind of file for the bump.
+	Editor = internal.Editor
+	// VCS commits and pushes the change of a checkout.
+	VCS = internal.VCS
+	// RemotePusher is implemented by a VCS which can push to the fork in
+	// fork mode.
+	RemotePusher = internal.RemotePusher
+	// Provider opens and inspects pull requests.
+	Provider = internal.Provider
+	// Forker is implemented by a Provider supporting fork mode.
+	Forker = internal.Forker
+	// Merger is implemented by a Provider which merges pull requests.
+	Merger = internal.Merger
+	// NewPullRequest is the pull request opened for a bump.
+	NewPullRequest = internal.NewPullRequest
+	// PullRequest is the state of a bump pull request.
+	PullRequest = internal.PullRequest
+	// Fork is the fork a bump branch is pushed to in fork mode.
+	Fork = internal.Fork
+
+	// Campaign persists the progress of a bump so it can be resumed.
+	Campaign = internal.Campaign
+	// RepoState is the progress of a repository in a campaign.
+	RepoState = internal.RepoState
+	// DepChange is a require directive changed by the dependency update.
+	DepChange = internal.DepChange
+
+	// ReleaseIndex resolves symbolic versions such as stable.
+	ReleaseIndex = internal.ReleaseIndex
+	// Release is a go release of the index.
+	Release = internal.Release
+	// EOLStatus is the support status of the go version of a repository.
+	EOLStatus = internal.EOLStatus
+)
+
+// Result statuses.
+const (
+	StatusDone    = "done"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+// Support statuses of a go version.
+const (
+	EOLSupported = internal.EOLSupported
+	EOLNearing   = internal.EOLNearing
+	EOLEnded     = internal.EOLEnded
+)
+
+// DefaultIndexURL is the official go release index.
+const DefaultIndexURL = internal.DefaultIndexURL
+
+// Options configure a Bumper. Planning needs either Path, a directory
+// holding one checkout per repository, or Remotes, clone URLs; applying a
+// plan only needs its Config.
+type Options struct {
+	Path    string
+	Remotes []string
+	Config  Config
+
+	// Campaign records the progress and skips repositories it completed.
+	Campaign *Campaign
+	// Editors run after the built-in go.mod, version and modernize editors.
+	Editors []Editor
+	// VCS is called with every checkout, hub is used when nil.
+	VCS func(dir string) VCS
+	// Provider replaces the provider named in the config.
+	Provider Provider
+	// Logger receives the progress, slog.Default() when nil.
+	Logger *slog.Logger
+	// Progress is told the stage of every repository.
+	Progress *Progress
+}
+
+// Bumper plans and applies a bump.
+type Bumper struct {
+	opts   Options
+	worker internal.Worker
+}
+
+// New returns a Bumper for the options. Config.Version must be a go
+// version, use a ReleaseIndex to resolve symbolic ones.
+func New(opts Options) (*Bumper, error) {
+	if opts.Config.Version == "" {
+		return nil, errors.New("gobump: no target version")
+	}
+
+	w := internal.NewWorker(opts.Path, opts.Config)
+	if len(opts.Remotes) > 0 {
+		if err := w.UseRemotes(opts.Remotes); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Campaign != nil {
+		w.UseCampaign(opts.Campaign)
+	}
+	w.UseEditors(opts.Editors...)
+	if opts.VCS != nil {
+		w.UseVCS(opts.VCS)
+	}
+	if opts.Provider != nil {
+		w.UseProvider(opts.Provider)
+	}
+	if opts.Logger != nil {
+		w.UseLogger(opts.Logger)
+	}
+	if opts.Progress != nil {
+		w.UseProgress(opts.Progress)
+	}
+
+	return &Bumper{opts: opts, worker: w}, nil
+}
+
+// Plan works out the change of every repository without touching them.
+func (b *Bumper) Plan(ctx context.Context) (*Plan, error) {
+	if b.opts.Path == "" && len(b.opts.Remotes) == 0 {
+		return nil, errors.New("gobump: either a path or remotes are required")
+	}
+
+	return b.worker.Plan(ctx)
+}
+
+// Apply edits, verifies, commits and pushes every planned repository and
+// opens its pull request. A failing repository doesn't stop the others,
+// its Result carries the error. A repository whose files changed since
+// planning fails; use Plan.Check to refuse a stale plan up front.
+func (b *Bumper) Apply(ctx context.Context, plan *Plan) ([]Result, error) {
+	return b.worker.Apply(ctx, plan)
+}
+
+// EOL reports the support status of the go version of every repository
+// with a go.mod at now. minors are the released go minors, newest first,
+// see ReleaseIndex.Minors and KnownMinors.
+func (b *Bumper) EOL(ctx context.Context, minors []string, now time.Time) ([]EOLStatus, error) {
+	if b.opts.Path == "" && len(b.opts.Remotes) == 0 {
+		return nil, errors.New("gobump: either a path or remotes are required")
+	}
+
+	return b.worker.EOL(ctx, minors, now)
+}
+
+// KnownMinors are the go minors of the release table built into gobump,
+// newest first, for when the release index can't be read.
+func KnownMinors() []string {
+	return internal.KnownMinors()
+}
+
+// ReadPlan reads a plan written with Plan.Write.
+func ReadPlan(path string) (*Plan, error) {
+	return internal.ReadPlan(path)
+}
+
+// NewLogger builds a text or json logger for the configured level.
+func NewLogger(cfg Log, out io.Writer) (*slog.Logger, error) {
+	return internal.NewLogger(cfg, out)
+}
+
+// NewProgress starts a live view on out, a terminal. Logs should be
+// written through it, see Progress.Write.
+func NewProgress(out io.Writer) *Progress {
+	return internal.NewProgress(out)
+}
+
+// Redact hides the tokens gobump resolved and anything looking like a
+// credential in s.
+func Redact(s string) string {
+	return internal.Redact(s)
+}
+
+// ParseIdentity parses `Name <email>`.
+func ParseIdentity(s string) (Identity, error) {
+	return internal.ParseIdentity(s)
+}
+
+// DefaultConfig returns the built-in defaults.
+func DefaultConfig() Config {
+	return internal.DefaultConfig()
+}
+
+// LoadConfig reads the config file at path, or the default location when
+// path is empty, over the defaults.
+func LoadConfig(path string) (Config, error) {
+	return internal.LoadConfig(path)
+}
+
+// NewReleaseIndex reads the release index at url, falling back to the cache
+// file, or reads the cache only when offline.
+func NewReleaseIndex(url, cache string, offline bool) ReleaseIndex {
+	return internal.NewReleaseIndex(url, cache, offline)
+}
+
+// ReadRepoList reads the repositories listed one per line in a file.
+func ReadRepoList(path string) ([]string, error) {
+	return internal.ReadRepoList(path)
+}
+
+// OrgRepos lists the clone URLs of the Go repositories of an organization.
+func OrgRepos(ctx context.Context, cfg Config, org string) ([]string, error) {
+	return internal.OrgRepos(ctx, cfg, org)
+}
+
+// NewCampaign starts a campaign, refusing to overwrite an existing one.
+func NewCampaign(stateDir, name, path string, remotes []string, cfg Config) (*Campaign, error) {
+	return internal.NewCampaign(stateDir, name, path, remotes, cfg)
+}
+
+// OpenCampaign loads a campaign from the state directory.
+func OpenCampaign(stateDir, name string) (*Campaign, error) {
+	return internal.OpenCampaign(stateDir, name)
+}
+
+// ListCampaigns returns the names of the campaigns in the state directory.
+func ListCampaigns(stateDir string) ([]string, error) {
+	return internal.ListCampaigns(stateDir)
+}